@@ -7,9 +7,12 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -19,17 +22,28 @@ import (
 )
 
 type Pagination struct {
-	Page       int `json:"Page"`       // текущий номер страницы
-	NewsOnPage int `json:"NewsOnPage"` // количество заголовков новостей на странице
-	TotalPages int `json:"TotalPages"` // общее число страниц
-	TotalNews  int `json:"TotalNews"`  // общее число новостей в БД
+	Page        int    `json:"Page,omitempty"`        // текущий номер страницы (нет в режиме курсора)
+	NewsOnPage  int    `json:"NewsOnPage"`            // количество заголовков новостей на странице
+	TotalPages  *int   `json:"TotalPages,omitempty"`  // общее число страниц (если известно общее число новостей)
+	TotalNews   *int   `json:"TotalNews,omitempty"`   // общее число новостей в БД (если запрошено)
+	TotalApprox bool   `json:"TotalApprox,omitempty"` // TotalNews - оценка, а не точное значение
+	NextCursor  string `json:"NextCursor,omitempty"`  // курсор следующей страницы
+	PrevCursor  string `json:"PrevCursor,omitempty"`  // курсор предыдущей страницы
 }
 
 // Коротко описывает новость для списка новостей.
 type NewsShortDetailed struct {
-	ID      int    `json:"ID"`      // уникальный идентификатор новости
-	Title   string `json:"Title"`   // заголовок новости
-	PubTime int64  `json:"PubTime"` // время новости
+	ID             int     `json:"ID"`                       // уникальный идентификатор новости
+	Title          string  `json:"Title"`                    // заголовок новости
+	PubTime        int64   `json:"PubTime"`                  // время новости
+	Rank           float32 `json:"Rank,omitempty"`           // ранг новости в результатах поиска
+	TitleHighlight string  `json:"TitleHighlight,omitempty"` // HTML: экранированный заголовок с выделенными (<b>) найденными словами
+	Snippet        string  `json:"Snippet,omitempty"`        // HTML: экранированный фрагмент содержания с выделенными найденными словами
+	Thumbnail      string  `json:"Thumbnail,omitempty"`      // миниатюра новости
+	Link           string  `json:"Link"`                     // ссылка на источник
+	Source         string  `json:"Source"`                   // источник новости (хост RSS-ленты)
+	UpdatedTime    int64   `json:"UpdatedTime,omitempty"`    // время последнего обновления новости в ленте
+	Summary        string  `json:"Summary,omitempty"`        // начало содержания без разметки (если запрошено)
 }
 
 // Структура для ответа на запрос списка новостей. С пагинацией.
@@ -47,6 +61,23 @@ type NewsFullDetailed struct {
 	PubTime int64  `json:"PubTime"` // время публикации новости
 	Link    string `json:"Link"`    // ссылка на источник
 	Error   int    `json:"Error"`   // данное поле служит для информирования клиента об ошибке
+
+	UpdatedTime int64    `json:"UpdatedTime"`     // время последнего обновления новости в ленте (0 - не обновлялась)
+	Media       []Media  `json:"Media,omitempty"` // изображения, аудио и видео новости
+	Tags        []string `json:"Tags,omitempty"`  // теги (категории) новости
+
+	PubTimeEstimated bool `json:"PubTimeEstimated,omitempty"` // дата публикации не распознана, PubTime - время получения новости
+}
+
+// Медиафайл новости.
+type Media struct {
+	URL       string `json:"URL"`                 // адрес файла
+	Type      string `json:"Type,omitempty"`      // MIME-тип
+	Medium    string `json:"Medium"`              // вид: image, audio, video
+	Size      int64  `json:"Size,omitempty"`      // размер в байтах
+	Width     int    `json:"Width,omitempty"`     // ширина изображения или видео
+	Height    int    `json:"Height,omitempty"`    // высота изображения или видео
+	Thumbnail bool   `json:"Thumbnail,omitempty"` // миниатюра новости
 }
 
 type Comment struct {
@@ -56,6 +87,50 @@ type Comment struct {
 	ParentCommentID int    `json:"ParentCommentID"` // уникальный идентификатор родительского комментария
 	PubTime         int64  `json:"PubTime"`         // время создания комментария (получаем от fontend)
 	Error           int    `json:"Error"`           // данное поле служит для информирования клиента об ошибке
+
+	Rejection *Rejection `json:"Rejection,omitempty"` // причина отклонения комментария проверкой
+	Masked    bool       `json:"Masked,omitempty"`    // комментарий опубликован с замаскированными запрещёнными словами
+}
+
+// Причина отклонения комментария сервисом проверки.
+type Rejection struct {
+	Reason  string         `json:"Reason"`            // код причины: banned_words, spam, duplicate, flood
+	Score   int            `json:"Score"`             // сумма серьёзности запрещённых слов и баллов признаков спама
+	Matches []VerdictMatch `json:"Matches,omitempty"` // найденные запрещённые слова
+	Spam    []SpamHit      `json:"Spam,omitempty"`    // найденные признаки спама
+}
+
+// Признак спама в комментарии. Позиции - в символах (Unicode code points) текста комментария.
+type SpamHit struct {
+	Check string `json:"Check"` // название проверки: urls, blocked_domain, caps, repeated_chars, phrase, max_length
+	Score int    `json:"Score"` // балл
+	Start int    `json:"Start"`
+	End   int    `json:"End"`
+	Text  string `json:"Text"` // найденный фрагмент или описание нарушения
+}
+
+// Найденное в комментарии запрещённое слово. Позиции - в символах (Unicode code points)
+// текста комментария, End - позиция за последним символом.
+type VerdictMatch struct {
+	Term     string `json:"Term"`     // слово словаря
+	Category string `json:"Category"` // категория слова
+	Severity int    `json:"Severity"` // серьёзность
+	Start    int    `json:"Start"`
+	End      int    `json:"End"`
+	Text     string `json:"Text"`   // найденный фрагмент комментария
+	Action   string `json:"Action"` // действие для категории слова: reject или mask
+}
+
+// Результат проверки наличия новости сервисом news.
+type NewsCheck struct {
+	ID     int    `json:"ID"`     // уникальный идентификатор новости
+	Source string `json:"Source"` // источник новости
+}
+
+// Результат успешной проверки комментария сервисом verification.
+type Verdict struct {
+	Masked  string `json:"Masked,omitempty"`  // текст комментария с замаскированными запрещёнными словами
+	CheckID string `json:"CheckID,omitempty"` // идентификатор проверки для подтверждения сохранения комментария
 }
 
 // Детальная информация по новости для структуры NewsComments. Без ошибки.
@@ -65,6 +140,12 @@ type News struct {
 	Content string `json:"Content"` // содержание новости
 	PubTime int64  `json:"PubTime"` // время публикации новости
 	Link    string `json:"Link"`    // ссылка на источник
+
+	UpdatedTime int64    `json:"UpdatedTime"`     // время последнего обновления новости в ленте (0 - не обновлялась)
+	Media       []Media  `json:"Media,omitempty"` // изображения, аудио и видео новости
+	Tags        []string `json:"Tags,omitempty"`  // теги (категории) новости
+
+	PubTimeEstimated bool `json:"PubTimeEstimated,omitempty"` // дата публикации не распознана, PubTime - время получения новости
 }
 
 // Для структуры NewsComments. Без ошибки.
@@ -76,6 +157,34 @@ type Comments struct {
 	PubTime         int64  `json:"PubTime"`         // время создания комментария (получаем от fontend)
 }
 
+// Тег и число новостей с ним.
+type TagCount struct {
+	Tag   string `json:"Tag"`
+	Count int    `json:"Count"`
+}
+
+// Структура для ответа на запрос списка тегов.
+type TagsList struct {
+	Tags  []TagCount `json:"Tags"`
+	Error int        `json:"Error"` // данное поле служит для информирования клиента об ошибке
+}
+
+// Прежняя редакция новости.
+type Revision struct {
+	ID          int    `json:"ID"`          // уникальный идентификатор редакции
+	NewsID      int    `json:"NewsID"`      // уникальный идентификатор новости
+	Title       string `json:"Title"`       // заголовок новости в этой редакции
+	Content     string `json:"Content"`     // содержание новости в этой редакции
+	RevisedTime int64  `json:"RevisedTime"` // время замены редакции новой
+}
+
+// Структура для ответа на запрос прежних редакций новости.
+type NewsRevisions struct {
+	NewsID    int        `json:"NewsID"`
+	Revisions []Revision `json:"Revisions"`
+	Error     int        `json:"Error"` // данное поле служит для информирования клиента об ошибке
+}
+
 type NewsComments struct {
 	N     News       `json:"News"`
 	C     []Comments `json:"Comments"`
@@ -102,11 +211,15 @@ func main() {
 	}()
 
 	r := mux.NewRouter()
-	r.HandleFunc("/newsList", myMiddleware(newsList)).Methods("GET")       // получение списка новостей
-	r.HandleFunc("/news", myMiddleware(fullNews)).Methods("GET")           // получение новости по id
-	r.HandleFunc("/comment", myMiddleware(comment)).Methods("GET")         // получение всех комментариев по id новости
-	r.HandleFunc("/add-comment", myMiddleware(addComment)).Methods("POST") // добавление комментария к новости
-	r.HandleFunc("/news+comments", myMiddleware(getFull)).Methods("GET")   // получение новости со всеми комментариями
+	r.HandleFunc("/newsList", myMiddleware(newsList)).Methods("GET")           // получение списка новостей
+	r.HandleFunc("/news", myMiddleware(fullNews)).Methods("GET")               // получение новости по id
+	r.HandleFunc("/comment", myMiddleware(comment)).Methods("GET")             // получение всех комментариев по id новости
+	r.HandleFunc("/add-comment", myMiddleware(addComment)).Methods("POST")     // добавление комментария к новости
+	r.HandleFunc("/news+comments", myMiddleware(getFull)).Methods("GET")       // получение новости со всеми комментариями
+	r.HandleFunc("/newsRevisions", myMiddleware(newsRevisions)).Methods("GET") // получение прежних редакций новости
+	r.HandleFunc("/tags", myMiddleware(tags)).Methods("GET")                   // получение списка тегов с числом новостей
+	r.HandleFunc("/feed.rss", myMiddleware(feedRSS)).Methods("GET")            // агрегированная лента новостей RSS 2.0
+	r.HandleFunc("/feed.atom", myMiddleware(feedAtom)).Methods("GET")          // агрегированная лента новостей Atom
 	http.Handle("/", r)
 	httpStart := fmt.Sprintf("HTTP server is started on localhost:%s", port)
 	fmt.Println(httpStart)
@@ -141,6 +254,15 @@ func myMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// clientIP возвращает IP-адрес клиента.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 // получение списка новостей
 func newsList(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -177,8 +299,75 @@ func newsList(w http.ResponseWriter, r *http.Request) {
 
 	search := r.URL.Query().Get("search")
 
-	url := fmt.Sprintf("http://news:8081/newsList?amount=%d&page=%d&search=%s&request_id=%s", amount, page, search, uniqueReqID)
-	resp, err := http.Get(url)
+	fromSTR := r.URL.Query().Get("from")
+	from, err := parseTimeParam(fromSTR)
+	if err != nil || from < 0 {
+		chErrs <- fmt.Errorf("request_id %s: нижняя граница времени публикации в url %s: %v", uniqueReqID, fromSTR, err)
+		returnError.Error = http.StatusBadRequest
+		w.WriteHeader(returnError.Error)
+		json.NewEncoder(w).Encode(returnError)
+		return
+	}
+
+	toSTR := r.URL.Query().Get("to")
+	to, err := parseTimeParam(toSTR)
+	if err != nil || to < 0 || (to != 0 && from > to) {
+		chErrs <- fmt.Errorf("request_id %s: верхняя граница времени публикации в url %s: %v", uniqueReqID, toSTR, err)
+		returnError.Error = http.StatusBadRequest
+		w.WriteHeader(returnError.Error)
+		json.NewEncoder(w).Encode(returnError)
+		return
+	}
+
+	sort := r.URL.Query().Get("sort")
+	if sort == "" {
+		sort = "desc"
+	}
+	// Сортировка по релевантности имеет смысл только при поиске.
+	if (sort != "desc" && sort != "asc" && sort != "relevance") || (sort == "relevance" && search == "") {
+		chErrs <- fmt.Errorf("request_id %s: недопустимый порядок сортировки в url %s (search=%q)", uniqueReqID, sort, search)
+		returnError.Error = http.StatusBadRequest
+		w.WriteHeader(returnError.Error)
+		json.NewEncoder(w).Encode(returnError)
+		return
+	}
+
+	// Постраничный вывод по курсору: первая страница запрашивается с paging=cursor,
+	// следующие - с курсором NextCursor/PrevCursor из предыдущего ответа.
+	cursor := r.URL.Query().Get("cursor")
+	paging := r.URL.Query().Get("paging")
+	if (paging != "" && paging != "page" && paging != "cursor") || ((cursor != "" || paging == "cursor") && sort == "relevance") {
+		chErrs <- fmt.Errorf("request_id %s: недопустимый режим пагинации в url %s (sort=%s)", uniqueReqID, paging, sort)
+		returnError.Error = http.StatusBadRequest
+		w.WriteHeader(returnError.Error)
+		json.NewEncoder(w).Encode(returnError)
+		return
+	}
+
+	total := r.URL.Query().Get("total")
+	if total != "" && total != "exact" && total != "approx" && total != "none" {
+		chErrs <- fmt.Errorf("request_id %s: недопустимый способ подсчёта новостей в url %s", uniqueReqID, total)
+		returnError.Error = http.StatusBadRequest
+		w.WriteHeader(returnError.Error)
+		json.NewEncoder(w).Encode(returnError)
+		return
+	}
+
+	query := url.Values{}
+	query.Set("amount", strconv.Itoa(amount))
+	query.Set("page", strconv.Itoa(page))
+	query.Set("search", search)
+	query.Set("from", strconv.FormatInt(from, 10))
+	query.Set("to", strconv.FormatInt(to, 10))
+	query.Set("sort", sort)
+	query.Set("tag", r.URL.Query().Get("tag"))
+	query.Set("source", r.URL.Query().Get("source"))
+	query.Set("summary", r.URL.Query().Get("summary"))
+	query.Set("paging", paging)
+	query.Set("cursor", cursor)
+	query.Set("total", total)
+	query.Set("request_id", uniqueReqID)
+	resp, err := http.Get("http://news:8081/newsList?" + query.Encode())
 	if err != nil {
 		chErrs <- fmt.Errorf("request_id %s: ошибка отправки запроса в news: %v", uniqueReqID, err)
 		returnError.Error = http.StatusInternalServerError
@@ -208,6 +397,23 @@ func newsList(w http.ResponseWriter, r *http.Request) {
 
 }
 
+// parseTimeParam разбирает время из параметра запроса: Unix-время в секундах или RFC3339.
+// Пустой параметр означает отсутствие ограничения (0).
+func parseTimeParam(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if sec, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return sec, nil
+	}
+	// Неэкранированный "+" смещения часового пояса приходит в url как пробел.
+	t, err := time.Parse(time.RFC3339, strings.Replace(s, " ", "+", 1))
+	if err != nil {
+		return 0, err
+	}
+	return t.Unix(), nil
+}
+
 // получение новости по id
 func fullNews(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -226,7 +432,16 @@ func fullNews(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	url := fmt.Sprintf("http://news:8081/news?news_id=%d&request_id=%s", news_id, uniqueReqID)
+	format, ok := contentFormat(r)
+	if !ok {
+		chErrs <- fmt.Errorf("request_id %s: формат содержания новости в url %s", uniqueReqID, format)
+		returnError.Error = http.StatusBadRequest
+		w.WriteHeader(returnError.Error)
+		json.NewEncoder(w).Encode(returnError)
+		return
+	}
+
+	url := fmt.Sprintf("http://news:8081/news?news_id=%d&format=%s&request_id=%s", news_id, format, uniqueReqID)
 	resp, err := http.Get(url)
 	if err != nil {
 		chErrs <- fmt.Errorf("request_id %s: ошибка отправки запроса в news: %v", uniqueReqID, err)
@@ -255,6 +470,141 @@ func fullNews(w http.ResponseWriter, r *http.Request) {
 	w.Write(body)
 }
 
+// получение списка тегов с числом новостей за промежуток времени
+func tags(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	uniqueReqID := r.Context().Value(uniqueID).(string)
+
+	var returnError TagsList
+
+	fromSTR := r.URL.Query().Get("from")
+	from, err := parseTimeParam(fromSTR)
+	if err != nil || from < 0 {
+		chErrs <- fmt.Errorf("request_id %s: нижняя граница времени публикации в url %s: %v", uniqueReqID, fromSTR, err)
+		returnError.Error = http.StatusBadRequest
+		w.WriteHeader(returnError.Error)
+		json.NewEncoder(w).Encode(returnError)
+		return
+	}
+
+	toSTR := r.URL.Query().Get("to")
+	to, err := parseTimeParam(toSTR)
+	if err != nil || to < 0 || (to != 0 && from > to) {
+		chErrs <- fmt.Errorf("request_id %s: верхняя граница времени публикации в url %s: %v", uniqueReqID, toSTR, err)
+		returnError.Error = http.StatusBadRequest
+		w.WriteHeader(returnError.Error)
+		json.NewEncoder(w).Encode(returnError)
+		return
+	}
+
+	limitSTR := r.URL.Query().Get("limit")
+	if limitSTR == "" {
+		limitSTR = "100"
+	}
+	limit, err := strconv.Atoi(limitSTR)
+	if err != nil || limit <= 0 {
+		chErrs <- fmt.Errorf("request_id %s: количество запрашиваемых тегов в url %s: %v", uniqueReqID, limitSTR, err)
+		returnError.Error = http.StatusBadRequest
+		w.WriteHeader(returnError.Error)
+		json.NewEncoder(w).Encode(returnError)
+		return
+	}
+
+	url := fmt.Sprintf("http://news:8081/tags?from=%d&to=%d&limit=%d&request_id=%s", from, to, limit, uniqueReqID)
+	resp, err := http.Get(url)
+	if err != nil {
+		chErrs <- fmt.Errorf("request_id %s: ошибка отправки запроса в news: %v", uniqueReqID, err)
+		returnError.Error = http.StatusInternalServerError
+		w.WriteHeader(returnError.Error)
+		json.NewEncoder(w).Encode(returnError)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		chErrs <- fmt.Errorf("request_id %s: ответ от news (status code): %d", uniqueReqID, resp.StatusCode)
+		returnError.Error = resp.StatusCode
+		w.WriteHeader(returnError.Error)
+		json.NewEncoder(w).Encode(returnError)
+		return
+	}
+
+	var out TagsList
+	err = json.NewDecoder(resp.Body).Decode(&out.Tags)
+	if err != nil {
+		chErrs <- fmt.Errorf("request_id %s: ошибка выполнения демаршалинга ответа от news: %v", uniqueReqID, err)
+		returnError.Error = http.StatusInternalServerError
+		w.WriteHeader(returnError.Error)
+		json.NewEncoder(w).Encode(returnError)
+		return
+	}
+	json.NewEncoder(w).Encode(out)
+}
+
+// contentFormat возвращает запрошенный формат содержания новости:
+// html - очищенный HTML (по умолчанию), text - текст без разметки.
+func contentFormat(r *http.Request) (string, bool) {
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "":
+		return "html", true
+	case "html", "text":
+		return format, true
+	default:
+		return format, false
+	}
+}
+
+// получение прежних редакций новости
+func newsRevisions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	uniqueReqID := r.Context().Value(uniqueID).(string)
+
+	var returnError NewsRevisions
+
+	news_idSTR := r.URL.Query().Get("news_id")
+	news_id, err := strconv.Atoi(news_idSTR)
+	if err != nil || news_id <= 0 {
+		chErrs <- fmt.Errorf("request_id %s: id новости в url %s: %v", uniqueReqID, news_idSTR, err)
+		returnError.Error = http.StatusBadRequest
+		w.WriteHeader(returnError.Error)
+		json.NewEncoder(w).Encode(returnError)
+		return
+	}
+
+	url := fmt.Sprintf("http://news:8081/newsRevisions?news_id=%d&request_id=%s", news_id, uniqueReqID)
+	resp, err := http.Get(url)
+	if err != nil {
+		chErrs <- fmt.Errorf("request_id %s: ошибка отправки запроса в news: %v", uniqueReqID, err)
+		returnError.Error = http.StatusInternalServerError
+		w.WriteHeader(returnError.Error)
+		json.NewEncoder(w).Encode(returnError)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		chErrs <- fmt.Errorf("request_id %s: ответ от news (status code): %d", uniqueReqID, resp.StatusCode)
+		returnError.Error = resp.StatusCode
+		w.WriteHeader(returnError.Error)
+		json.NewEncoder(w).Encode(returnError)
+		return
+	}
+
+	out := NewsRevisions{NewsID: news_id}
+	err = json.NewDecoder(resp.Body).Decode(&out.Revisions)
+	if err != nil {
+		chErrs <- fmt.Errorf("request_id %s: ошибка выполнения демаршалинга ответа от news: %v", uniqueReqID, err)
+		returnError.Error = http.StatusInternalServerError
+		w.WriteHeader(returnError.Error)
+		json.NewEncoder(w).Encode(returnError)
+		return
+	}
+	json.NewEncoder(w).Encode(out)
+}
+
 // получение всех комментариев по id новости
 func comment(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -320,10 +670,13 @@ func addComment(w http.ResponseWriter, r *http.Request) {
 
 	// Асинхронный запуск:
 	// - проверки наличия родительского комментария в БД
-	// - проверки наличия новости в БД
-	// - проверки комментария на запрещённые слова.
+	// - проверки наличия новости в БД и затем проверки комментария на запрещённые слова.
 	var wg sync.WaitGroup
 	checkChan := make(chan int, 3)
+	var rejection *Rejection // заполняется при отклонении комментария проверкой
+	var masked string        // текст комментария с замаскированными словами
+	var checkID string       // идентификатор проверки для подтверждения сохранения комментария
+	newsSource := make(chan string, 1)
 
 	// Проверяем наличие родительского комментария.
 	wg.Add(1)
@@ -345,10 +698,11 @@ func addComment(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
-	// Проверяем наличие новости в БД.
+	// Проверяем наличие новости в БД. Источник новости передаётся проверке комментария.
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
+		defer close(newsSource)
 		urlNewsCheck := fmt.Sprintf("http://news:8081/newsCheck?news_id=%d&request_id=%s", C.NewsID, uniqueReqID)
 		respNewsCheck, err := http.Get(urlNewsCheck)
 		if err != nil {
@@ -356,19 +710,44 @@ func addComment(w http.ResponseWriter, r *http.Request) {
 			checkChan <- http.StatusInternalServerError
 			return
 		}
+		defer respNewsCheck.Body.Close()
 
 		if respNewsCheck.StatusCode != http.StatusOK {
 			chErrs <- fmt.Errorf("request_id %s: новость (news ID %d) отсутствует в БД", uniqueReqID, C.NewsID)
 			checkChan <- respNewsCheck.StatusCode
+			return
 		}
+		var check NewsCheck
+		err = json.NewDecoder(respNewsCheck.Body).Decode(&check)
+		if err != nil {
+			chErrs <- fmt.Errorf("request_id %s: ошибка декодирования ответа от news: %v", uniqueReqID, err)
+			checkChan <- http.StatusInternalServerError
+			return
+		}
+		newsSource <- check.Source
 	}()
 
-	// Проверяем комментарий на наличие запрещённых слов.
+	// Проверяем комментарий на наличие запрещённых слов после проверки новости:
+	// политика проверки зависит от новости и её источника.
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		url := fmt.Sprintf("http://verification:8083/verification?request_id=%s", uniqueReqID)
-		check, err := http.Post(url, "application/json", bytes.NewBuffer(newData))
+		source, ok := <-newsSource
+		if !ok {
+			// новость не найдена, ошибка уже передана
+			return
+		}
+		url := fmt.Sprintf("http://verification:8083/verification?request_id=%s&source=%s", uniqueReqID, url.QueryEscape(source))
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(newData))
+		if err != nil {
+			chErrs <- fmt.Errorf("request_id %s: ошибка создания запроса в verification %v", uniqueReqID, err)
+			checkChan <- http.StatusInternalServerError
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		// IP-адрес автора для проверки повторов и флуда.
+		req.Header.Set("X-Real-IP", clientIP(r))
+		check, err := http.DefaultClient.Do(req)
 		if err != nil {
 			chErrs <- fmt.Errorf("request_id %s: ошибка отправки запроса в verification %v", uniqueReqID, err)
 			checkChan <- http.StatusInternalServerError
@@ -379,14 +758,40 @@ func addComment(w http.ResponseWriter, r *http.Request) {
 		if check.StatusCode == http.StatusInternalServerError {
 			checkChan <- http.StatusInternalServerError
 		} else if check.StatusCode == http.StatusBadRequest {
+			var verdict Rejection
+			err := json.NewDecoder(check.Body).Decode(&verdict)
+			if err != nil {
+				chErrs <- fmt.Errorf("request_id %s: ошибка декодирования ответа от verification: %v", uniqueReqID, err)
+			} else {
+				rejection = &verdict
+			}
 			chErrs <- fmt.Errorf("request_id %s: комментарий не прошёл проверку (status code): %d", uniqueReqID, check.StatusCode)
 			checkChan <- check.StatusCode
+		} else if check.StatusCode == http.StatusOK {
+			var verdict Verdict
+			err := json.NewDecoder(check.Body).Decode(&verdict)
+			if err != nil {
+				chErrs <- fmt.Errorf("request_id %s: ошибка декодирования ответа от verification: %v", uniqueReqID, err)
+				checkChan <- http.StatusInternalServerError
+				return
+			}
+			masked = verdict.Masked
+			checkID = verdict.CheckID
 		}
 	}()
 
 	wg.Wait()
 	close(checkChan)
 
+	// Сервису verification сообщается, сохранён ли принятый им комментарий:
+	// комментарий, не сохранённый из-за других проверок или ошибки, не считается повтором.
+	stored := false
+	defer func() {
+		if checkID != "" {
+			confirmVerification(uniqueReqID, checkID, stored)
+		}
+	}()
+
 	var returnError Comment
 	for data := range checkChan {
 		if data == 404 {
@@ -397,6 +802,7 @@ func addComment(w http.ResponseWriter, r *http.Request) {
 		}
 		if data == 400 {
 			returnError.Error = http.StatusBadRequest
+			returnError.Rejection = rejection
 			w.WriteHeader(returnError.Error)
 			json.NewEncoder(w).Encode(returnError)
 			return
@@ -409,6 +815,17 @@ func addComment(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Для слов с действием mask сохраняется текст с замаскированными словами.
+	if masked != "" {
+		C.Comment = masked
+		newData, err = json.Marshal(C)
+		if err != nil {
+			chErrs <- fmt.Errorf("request_id %s: ошибка выполнения маршалинга", uniqueReqID)
+		}
+		returnError.Comment = masked
+		returnError.Masked = true
+	}
+
 	// Добавляем комментарий если проверки пройдены.
 	url := fmt.Sprintf("http://comments:8082/add-comment?request_id=%s", uniqueReqID)
 	resp, err := http.Post(url, "application/json", bytes.NewBuffer(newData))
@@ -429,12 +846,29 @@ func addComment(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(returnError)
 		return
 	} else {
+		stored = true
 		returnError.Error = 0
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(returnError)
 	}
 }
 
+// confirmVerification сообщает сервису verification, сохранён ли комментарий,
+// принятый проверкой checkID.
+func confirmVerification(uniqueReqID, checkID string, stored bool) {
+	url := fmt.Sprintf("http://verification:8083/verification/confirm?request_id=%s&check_id=%s&stored=%t", uniqueReqID, url.QueryEscape(checkID), stored)
+	resp, err := http.Post(url, "application/json", nil)
+	if err != nil {
+		chErrs <- fmt.Errorf("request_id %s: ошибка отправки подтверждения в verification %v", uniqueReqID, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		chErrs <- fmt.Errorf("request_id %s: ответ от verification на подтверждение (status code): %d", uniqueReqID, resp.StatusCode)
+	}
+}
+
 // получение новости со всеми комментариями
 func getFull(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -456,6 +890,15 @@ func getFull(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	format, ok := contentFormat(r)
+	if !ok {
+		chErrs <- fmt.Errorf("request_id %s: формат содержания новости в url %s", uniqueReqID, format)
+		returnError.Error = http.StatusBadRequest
+		w.WriteHeader(returnError.Error)
+		json.NewEncoder(w).Encode(returnError)
+		return
+	}
+
 	// Асинхронный запуск:
 	// - получение новости по id
 	// - получение всех комментариев к новости.
@@ -467,7 +910,7 @@ func getFull(w http.ResponseWriter, r *http.Request) {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		url := fmt.Sprintf("http://news:8081/news?news_id=%d&request_id=%s", news_id, uniqueReqID)
+		url := fmt.Sprintf("http://news:8081/news?news_id=%d&format=%s&request_id=%s", news_id, format, uniqueReqID)
 		resp, err := http.Get(url)
 		if err != nil {
 			chErrs <- fmt.Errorf("request_id %s: ошибка отправки запроса в news: %v", uniqueReqID, err)
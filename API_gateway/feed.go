@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Название агрегированной ленты.
+const feedTitle = "News Aggregator"
+
+// Число новостей в ленте по умолчанию и наибольшее допустимое.
+const (
+	feedDefaultAmount = 20
+	feedMaxAmount     = 100
+)
+
+// Время, в течение которого клиенты и прокси могут не перезапрашивать ленту (в секундах).
+const feedMaxAge = 300
+
+// Документ RSS 2.0. Элементы из других пространств имён (atom:link, dc:creator)
+// объявлены в тегах полей по URL пространства имён: encoding/xml не поддерживает
+// префиксы и выводит их с атрибутом xmlns, например <link xmlns="http://www.w3.org/2005/Atom" .../>.
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string      `xml:"title"`
+	Link          string      `xml:"link"`
+	Description   string      `xml:"description"`
+	LastBuildDate string      `xml:"lastBuildDate,omitempty"`
+	TTL           int         `xml:"ttl"`
+	AtomLink      rssAtomLink `xml:"http://www.w3.org/2005/Atom link"`
+	Items         []rssItem   `xml:"item"`
+}
+
+// Ссылка ленты на саму себя (рекомендуется валидаторами RSS).
+type rssAtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description,omitempty"`
+	Author      string  `xml:"http://purl.org/dc/elements/1.1/ creator,omitempty"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// Документ Atom.
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomPerson  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	Title     string      `xml:"title"`
+	ID        string      `xml:"id"`
+	Updated   string      `xml:"updated"`
+	Published string      `xml:"published"`
+	Links     []atomLink  `xml:"link"`
+	Author    *atomPerson `xml:"author,omitempty"`
+	Summary   *atomText   `xml:"summary,omitempty"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// Формирует документ ленты из списка новостей. self - адрес ленты, updated - время последнего изменения.
+type feedRenderer func(news []NewsShortDetailed, self string, updated time.Time) interface{}
+
+// агрегированная лента новостей в формате RSS 2.0
+func feedRSS(w http.ResponseWriter, r *http.Request) {
+	feed(w, r, "application/rss+xml; charset=utf-8", renderRSS)
+}
+
+// агрегированная лента новостей в формате Atom
+func feedAtom(w http.ResponseWriter, r *http.Request) {
+	feed(w, r, "application/atom+xml; charset=utf-8", renderAtom)
+}
+
+// feed получает последние новости с учётом фильтров search, source и tag
+// и отдаёт их лентой. Поддерживаются условные запросы (If-None-Match, If-Modified-Since).
+func feed(w http.ResponseWriter, r *http.Request, contentType string, render feedRenderer) {
+	uniqueReqID := r.Context().Value(uniqueID).(string)
+
+	amount := feedDefaultAmount
+	if amountSTR := r.URL.Query().Get("amount"); amountSTR != "" {
+		var err error
+		amount, err = strconv.Atoi(amountSTR)
+		if err != nil || amount <= 0 || amount > feedMaxAmount {
+			chErrs <- fmt.Errorf("request_id %s: количество новостей ленты в url %s: %v", uniqueReqID, amountSTR, err)
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+	}
+
+	query := url.Values{}
+	query.Set("amount", strconv.Itoa(amount))
+	query.Set("page", "1")
+	query.Set("sort", "desc")
+	query.Set("total", "none")
+	query.Set("summary", "true")
+	query.Set("search", r.URL.Query().Get("search"))
+	query.Set("source", r.URL.Query().Get("source"))
+	query.Set("tag", r.URL.Query().Get("tag"))
+	query.Set("request_id", uniqueReqID)
+	resp, err := http.Get("http://news:8081/newsList?" + query.Encode())
+	if err != nil {
+		chErrs <- fmt.Errorf("request_id %s: ошибка отправки запроса в news: %v", uniqueReqID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		chErrs <- fmt.Errorf("request_id %s: ответ от news (status code): %d", uniqueReqID, resp.StatusCode)
+		http.Error(w, http.StatusText(resp.StatusCode), resp.StatusCode)
+		return
+	}
+
+	var list PaginationNewsList
+	err = json.NewDecoder(resp.Body).Decode(&list)
+	if err != nil {
+		chErrs <- fmt.Errorf("request_id %s: ошибка декодирования ответа от news: %v", uniqueReqID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	// Лента изменилась не позднее последней публикации или обновления новости в ней.
+	var updated time.Time
+	for _, n := range list.NewsList {
+		for _, t := range []int64{n.PubTime, n.UpdatedTime} {
+			if t > updated.Unix() {
+				updated = time.Unix(t, 0).UTC()
+			}
+		}
+	}
+
+	body, err := encodeFeed(render(list.NewsList, selfURL(r), updated))
+	if err != nil {
+		chErrs <- fmt.Errorf("request_id %s: ошибка формирования ленты: %v", uniqueReqID, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", feedMaxAge))
+	w.Header().Set("ETag", etag)
+	if !updated.IsZero() {
+		w.Header().Set("Last-Modified", updated.Format(http.TimeFormat))
+	}
+	if notModified(r, etag, updated) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(body)
+}
+
+// encodeFeed кодирует документ ленты в XML с заголовком <?xml ...?>.
+func encodeFeed(doc interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// notModified сообщает, что у клиента актуальная версия ленты.
+// If-None-Match имеет приоритет над If-Modified-Since (RFC 9110).
+func notModified(r *http.Request, etag string, updated time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == etag || tag == "*" {
+				return true
+			}
+		}
+		return false
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !updated.IsZero() {
+		t, err := http.ParseTime(ims)
+		return err == nil && !updated.After(t)
+	}
+	return false
+}
+
+// selfURL возвращает адрес запрошенной ленты без служебного параметра request_id.
+func selfURL(r *http.Request) string {
+	scheme := "http"
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	} else if r.TLS != nil {
+		scheme = "https"
+	}
+	query := r.URL.Query()
+	query.Del("request_id")
+	u := url.URL{Scheme: scheme, Host: r.Host, Path: r.URL.Path, RawQuery: query.Encode()}
+	return u.String()
+}
+
+// renderRSS формирует ленту RSS 2.0. Источник новости указывается в dc:creator.
+func renderRSS(news []NewsShortDetailed, self string, updated time.Time) interface{} {
+	doc := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       feedTitle,
+			Link:        self,
+			Description: "Новости, собранные из RSS-лент агрегатором",
+			TTL:         feedMaxAge / 60,
+			AtomLink:    rssAtomLink{Href: self, Rel: "self", Type: "application/rss+xml"},
+		},
+	}
+	if !updated.IsZero() {
+		doc.Channel.LastBuildDate = updated.Format(time.RFC1123Z)
+	}
+	for _, n := range news {
+		doc.Channel.Items = append(doc.Channel.Items, rssItem{
+			Title:       n.Title,
+			Link:        n.Link,
+			Description: n.Summary,
+			Author:      n.Source,
+			GUID:        rssGUID{IsPermaLink: true, Value: n.Link},
+			PubDate:     time.Unix(n.PubTime, 0).UTC().Format(time.RFC1123Z),
+		})
+	}
+	return doc
+}
+
+// renderAtom формирует ленту Atom. Источник новости указывается её автором.
+func renderAtom(news []NewsShortDetailed, self string, updated time.Time) interface{} {
+	if updated.IsZero() {
+		updated = time.Now().UTC()
+	}
+	doc := atomFeed{
+		Title:   feedTitle,
+		ID:      self,
+		Updated: updated.Format(time.RFC3339),
+		Links:   []atomLink{{Href: self, Rel: "self", Type: "application/atom+xml"}},
+		Author:  atomPerson{Name: feedTitle},
+	}
+	for _, n := range news {
+		published := time.Unix(n.PubTime, 0).UTC()
+		entryUpdated := published
+		if n.UpdatedTime > n.PubTime {
+			entryUpdated = time.Unix(n.UpdatedTime, 0).UTC()
+		}
+		e := atomEntry{
+			Title:     n.Title,
+			ID:        n.Link,
+			Updated:   entryUpdated.Format(time.RFC3339),
+			Published: published.Format(time.RFC3339),
+			Links:     []atomLink{{Href: n.Link, Rel: "alternate"}},
+		}
+		if n.Source != "" {
+			e.Author = &atomPerson{Name: n.Source}
+		}
+		if n.Summary != "" {
+			e.Summary = &atomText{Type: "text", Value: n.Summary}
+		}
+		doc.Entries = append(doc.Entries, e)
+	}
+	return doc
+}
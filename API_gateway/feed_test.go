@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/xml"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_notModified(t *testing.T) {
+	const etag = `"0123456789abcdef"`
+	updated := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		inm     string // If-None-Match
+		ims     string // If-Modified-Since
+		updated time.Time
+		want    bool
+	}{
+		{name: "без условий", updated: updated, want: false},
+		// If-None-Match
+		{name: "совпадающий ETag", inm: etag, updated: updated, want: true},
+		{name: "другой ETag", inm: `"fedcba9876543210"`, updated: updated, want: false},
+		{name: "слабый ETag", inm: `W/` + etag, updated: updated, want: true},
+		{name: "список ETag", inm: `"fedcba9876543210", W/` + etag, updated: updated, want: true},
+		{name: "звёздочка", inm: "*", updated: updated, want: true},
+		{name: "ETag без кавычек", inm: "0123456789abcdef", updated: updated, want: false},
+		// If-Modified-Since
+		{name: "не изменилась с указанного времени", ims: "Fri, 01 Mar 2024 12:00:00 GMT", updated: updated, want: true},
+		{name: "время клиента позже изменения", ims: "Sat, 02 Mar 2024 00:00:00 GMT", updated: updated, want: true},
+		{name: "изменилась после указанного времени", ims: "Fri, 01 Mar 2024 11:59:59 GMT", updated: updated, want: false},
+		{name: "неразборчивое время", ims: "вчера", updated: updated, want: false},
+		{name: "время изменения неизвестно", ims: "Fri, 01 Mar 2024 12:00:00 GMT", want: false},
+		// приоритет If-None-Match
+		{name: "другой ETag при актуальном времени", inm: `"fedcba9876543210"`, ims: "Sat, 02 Mar 2024 00:00:00 GMT", updated: updated, want: false},
+		{name: "совпадающий ETag при устаревшем времени", inm: etag, ims: "Thu, 01 Feb 2024 00:00:00 GMT", updated: updated, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/feed.rss", nil)
+			if tt.inm != "" {
+				r.Header.Set("If-None-Match", tt.inm)
+			}
+			if tt.ims != "" {
+				r.Header.Set("If-Modified-Since", tt.ims)
+			}
+			if got := notModified(r, etag, tt.updated); got != tt.want {
+				t.Errorf("notModified(If-None-Match %q, If-Modified-Since %q) = %v, want %v", tt.inm, tt.ims, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_selfURL(t *testing.T) {
+	r := httptest.NewRequest("GET", "http://gw.example.com/feed.rss?tag=sport&request_id=123", nil)
+	if got, want := selfURL(r), "http://gw.example.com/feed.rss?tag=sport"; got != want {
+		t.Errorf("selfURL() = %q, want %q", got, want)
+	}
+	r.Header.Set("X-Forwarded-Proto", "https")
+	if got, want := selfURL(r), "https://gw.example.com/feed.rss?tag=sport"; got != want {
+		t.Errorf("selfURL() с X-Forwarded-Proto = %q, want %q", got, want)
+	}
+}
+
+// Новости для проверки лент: вторая обновлялась после публикации и не имеет источника и описания.
+var testFeedNews = []NewsShortDetailed{
+	{
+		ID:      1,
+		Title:   "Заголовок <первой> & новости",
+		PubTime: time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC).Unix(),
+		Link:    "https://lenta.example/news/1",
+		Source:  "lenta.example",
+		Summary: "Начало первой новости",
+	},
+	{
+		ID:          2,
+		Title:       "Вторая новость",
+		PubTime:     time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC).Unix(),
+		UpdatedTime: time.Date(2024, 3, 1, 11, 0, 0, 0, time.UTC).Unix(),
+		Link:        "https://rbc.example/news/2",
+	},
+}
+
+const testFeedSelf = "http://gw.example.com/feed.rss?tag=sport"
+
+// Документ RSS, разбираемый с учётом пространств имён элементов.
+type testRSS struct {
+	XMLName xml.Name `xml:"rss"`
+	Version string   `xml:"version,attr"`
+	Channel struct {
+		// atom:link объявлен раньше link: поле без пространства имён совпадает с элементом из любого.
+		AtomLink struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+			Type string `xml:"type,attr"`
+		} `xml:"http://www.w3.org/2005/Atom link"`
+		Title         string `xml:"title"`
+		Link          string `xml:"link"`
+		LastBuildDate string `xml:"lastBuildDate"`
+		TTL           int    `xml:"ttl"`
+		Items         []struct {
+			Title       string `xml:"title"`
+			Link        string `xml:"link"`
+			Description string `xml:"description"`
+			Creator     string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+			GUID        struct {
+				IsPermaLink string `xml:"isPermaLink,attr"`
+				Value       string `xml:",chardata"`
+			} `xml:"guid"`
+			PubDate string `xml:"pubDate"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+func Test_renderRSS(t *testing.T) {
+	updated := time.Date(2024, 3, 1, 11, 0, 0, 0, time.UTC)
+	body, err := encodeFeed(renderRSS(testFeedNews, testFeedSelf, updated))
+	if err != nil {
+		t.Fatalf("encodeFeed() error = %v", err)
+	}
+	if !strings.HasPrefix(string(body), xml.Header) {
+		t.Errorf("лента не начинается с заголовка XML: %.40q", body)
+	}
+	var doc testRSS
+	if err = xml.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("xml.Unmarshal() error = %v\n%s", err, body)
+	}
+
+	ch := doc.Channel
+	if doc.Version != "2.0" || ch.Title != feedTitle || ch.Link != testFeedSelf || ch.TTL != feedMaxAge/60 {
+		t.Errorf("version, title, link, ttl = %q, %q, %q, %d", doc.Version, ch.Title, ch.Link, ch.TTL)
+	}
+	if ch.LastBuildDate != "Fri, 01 Mar 2024 11:00:00 +0000" {
+		t.Errorf("lastBuildDate = %q", ch.LastBuildDate)
+	}
+	if ch.AtomLink.Href != testFeedSelf || ch.AtomLink.Rel != "self" || ch.AtomLink.Type != "application/rss+xml" {
+		t.Errorf("atom:link = %+v", ch.AtomLink)
+	}
+	if len(ch.Items) != 2 {
+		t.Fatalf("item: %d, want 2", len(ch.Items))
+	}
+	item := ch.Items[0]
+	if item.Title != testFeedNews[0].Title || item.Link != testFeedNews[0].Link || item.Description != testFeedNews[0].Summary {
+		t.Errorf("title, link, description = %q, %q, %q", item.Title, item.Link, item.Description)
+	}
+	if item.Creator != "lenta.example" {
+		t.Errorf("dc:creator = %q, want %q", item.Creator, "lenta.example")
+	}
+	if item.GUID.IsPermaLink != "true" || item.GUID.Value != testFeedNews[0].Link {
+		t.Errorf("guid = %+v", item.GUID)
+	}
+	if item.PubDate != "Fri, 01 Mar 2024 09:00:00 +0000" {
+		t.Errorf("pubDate = %q", item.PubDate)
+	}
+	// пустые источник и описание не выводятся
+	if s := string(body); strings.Count(s, "<creator") != 1 || strings.Count(s, "<description>") != 2 {
+		t.Errorf("лишние элементы creator или description:\n%s", body)
+	}
+}
+
+func Test_renderRSS_noNews(t *testing.T) {
+	body, err := encodeFeed(renderRSS(nil, testFeedSelf, time.Time{}))
+	if err != nil {
+		t.Fatalf("encodeFeed() error = %v", err)
+	}
+	var doc testRSS
+	if err = xml.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("xml.Unmarshal() error = %v", err)
+	}
+	if doc.Channel.LastBuildDate != "" || len(doc.Channel.Items) != 0 {
+		t.Errorf("lastBuildDate = %q, item: %d, want пустую ленту", doc.Channel.LastBuildDate, len(doc.Channel.Items))
+	}
+}
+
+func Test_renderAtom(t *testing.T) {
+	updated := time.Date(2024, 3, 1, 11, 0, 0, 0, time.UTC)
+	body, err := encodeFeed(renderAtom(testFeedNews, testFeedSelf, updated))
+	if err != nil {
+		t.Fatalf("encodeFeed() error = %v", err)
+	}
+	// Разбор в общие для сервиса структуры проверяет и пространство имён корня.
+	var doc atomFeed
+	if err = xml.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("xml.Unmarshal() error = %v\n%s", err, body)
+	}
+	if doc.XMLName.Space != "http://www.w3.org/2005/Atom" {
+		t.Errorf("пространство имён feed = %q", doc.XMLName.Space)
+	}
+	if doc.Title != feedTitle || doc.ID != testFeedSelf || doc.Updated != "2024-03-01T11:00:00Z" || doc.Author.Name != feedTitle {
+		t.Errorf("title, id, updated, author = %q, %q, %q, %q", doc.Title, doc.ID, doc.Updated, doc.Author.Name)
+	}
+	if len(doc.Links) != 1 || doc.Links[0] != (atomLink{Href: testFeedSelf, Rel: "self", Type: "application/atom+xml"}) {
+		t.Errorf("link = %+v", doc.Links)
+	}
+	if len(doc.Entries) != 2 {
+		t.Fatalf("entry: %d, want 2", len(doc.Entries))
+	}
+
+	first, second := doc.Entries[0], doc.Entries[1]
+	if first.Title != testFeedNews[0].Title || first.ID != testFeedNews[0].Link {
+		t.Errorf("title, id = %q, %q", first.Title, first.ID)
+	}
+	if len(first.Links) != 1 || first.Links[0] != (atomLink{Href: testFeedNews[0].Link, Rel: "alternate"}) {
+		t.Errorf("link = %+v", first.Links)
+	}
+	if first.Published != "2024-03-01T09:00:00Z" || first.Updated != "2024-03-01T09:00:00Z" {
+		t.Errorf("published, updated = %q, %q", first.Published, first.Updated)
+	}
+	if first.Author == nil || first.Author.Name != "lenta.example" {
+		t.Errorf("author = %+v, want lenta.example", first.Author)
+	}
+	if first.Summary == nil || *first.Summary != (atomText{Type: "text", Value: testFeedNews[0].Summary}) {
+		t.Errorf("summary = %+v", first.Summary)
+	}
+	// обновлённая новость без источника и описания
+	if second.Published != "2024-03-01T10:00:00Z" || second.Updated != "2024-03-01T11:00:00Z" {
+		t.Errorf("published, updated = %q, %q", second.Published, second.Updated)
+	}
+	if second.Author != nil || second.Summary != nil {
+		t.Errorf("author, summary = %+v, %+v, want nil", second.Author, second.Summary)
+	}
+}
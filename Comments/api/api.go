@@ -38,9 +38,10 @@ func (api *API) Router() *mux.Router {
 
 // Регистрация методов API в маршрутизаторе запросов.
 func (api *API) endpoints() {
-	api.r.HandleFunc("/comments", api.comments).Methods("GET")           // получение всех комментариев по id новости
-	api.r.HandleFunc("/commentsCheck", api.commentsCheck).Methods("GET") // проверка наличия комментария в БД для новости
-	api.r.HandleFunc("/add-comment", api.addComment).Methods("POST")     // добавление комментария к новости
+	api.r.HandleFunc("/comments", api.comments).Methods("GET")            // получение всех комментариев по id новости
+	api.r.HandleFunc("/commentsCheck", api.commentsCheck).Methods("GET")  // проверка наличия комментария в БД для новости
+	api.r.HandleFunc("/add-comment", api.addComment).Methods("POST")      // добавление комментария к новости
+	api.r.HandleFunc("/commentedNews", api.commentedNews).Methods("POST") // отбор новостей, к которым есть комментарии
 }
 
 // получение всех комментариев по id новости
@@ -129,3 +130,29 @@ func (api *API) addComment(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}
 }
+
+// отбор новостей, к которым есть комментарии: в теле запроса - массив id новостей,
+// в ответе - те из них, к которым есть комментарии
+func (api *API) commentedNews(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	uniqueReqID := r.URL.Query().Get("request_id")
+
+	var news_ids []int
+	err := json.NewDecoder(r.Body).Decode(&news_ids)
+	if err != nil {
+		log.Printf("request_id %s: ошибка декодирования json: %v", uniqueReqID, err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	ids, err := api.db.CommentedNews(news_ids, uniqueReqID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if ids == nil {
+		ids = []int{}
+	}
+	json.NewEncoder(w).Encode(ids)
+}
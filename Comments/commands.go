@@ -0,0 +1,34 @@
+package main
+
+import (
+	"APIGateway/Comments/storage"
+	"APIGateway/pkg/migrate"
+	"errors"
+	"os"
+)
+
+// Справка по подкомандам.
+const usage = `использование:
+  comments                      запуск сервиса
+  comments migrate up           применить миграции схемы БД
+  comments migrate down [N]     отменить N последних миграций (по умолчанию - одну)
+  comments migrate status       состояние миграций`
+
+// runCommand выполняет подкоманду командной строки.
+func runCommand(args []string) error {
+	if len(args) < 2 || args[0] != "migrate" {
+		return errors.New(usage)
+	}
+	return migrateCommand(args[1:])
+}
+
+// migrateCommand применяет, отменяет миграции схемы БД или выводит их состояние.
+func migrateCommand(args []string) error {
+	err := migrate.Command(args, func() (migrate.Runner, error) {
+		return storage.New()
+	}, os.Stdout)
+	if errors.Is(err, migrate.ErrUsage) {
+		return errors.New(usage)
+	}
+	return err
+}
@@ -10,9 +10,20 @@ import (
 )
 
 var port = os.Getenv("API_PORT")
+var autoMigrate = os.Getenv("DB_AUTO_MIGRATE") != "false"
 
 func main() {
 
+	// Подкоманды командной строки (миграции схемы БД).
+	if len(os.Args) > 1 {
+		err := runCommand(os.Args[1:])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Создаём канал для агрегации ошибок.
 	chErrs := make(chan error)
 
@@ -26,7 +37,15 @@ func main() {
 	// Реляционная БД PostgreSQL.
 	db, err := storage.New()
 	if err != nil {
-		chErrs <- fmt.Errorf("ошибка подключения к БД:  %v", err)
+		log.Fatalf("ошибка подключения к БД:  %v", err)
+	}
+
+	// Миграции схемы БД при запуске (отключаются переменной окружения DB_AUTO_MIGRATE=false).
+	if autoMigrate {
+		_, err = db.MigrateUp()
+		if err != nil {
+			log.Fatalf("ошибка миграции схемы БД:  %v", err)
+		}
 	}
 
 	api := api.New(db)
@@ -0,0 +1,3 @@
+-- Отмена базовой схемы: удаление таблицы комментариев вместе с данными.
+
+DROP TABLE IF EXISTS comments;
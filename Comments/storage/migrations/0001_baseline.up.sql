@@ -0,0 +1,17 @@
+-- Базовая схема БД комментариев.
+-- Объекты создаются с IF NOT EXISTS, чтобы миграция применялась и к БД,
+-- созданной ранее из schema.sql, без потери данных.
+
+-- комментарии
+CREATE TABLE IF NOT EXISTS comments (
+    id SERIAL PRIMARY KEY,
+    news_id INT,
+    comment TEXT,
+    parent_comment_id INT,
+    pub_time INTEGER DEFAULT 0
+);
+
+-- выборка комментариев новости
+CREATE INDEX IF NOT EXISTS comments_news_id_idx ON comments (news_id);
+
+INSERT INTO comments (id) VALUES (0) ON CONFLICT (id) DO NOTHING;
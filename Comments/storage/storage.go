@@ -137,3 +137,31 @@ func (s *Storage) AddComment(c Comment, uniqueReqID string) error {
 	}
 	return nil
 }
+
+// CommentedNews возвращает те из указанных новостей, к которым есть комментарии.
+func (s *Storage) CommentedNews(news_ids []int, uniqueReqID string) ([]int, error) {
+	rows, err := s.db.Query(context.Background(), `
+		SELECT DISTINCT
+			news_id
+		FROM comments
+		WHERE news_id = ANY($1);
+	`, news_ids,
+	)
+	if err != nil {
+		log.Printf("request_id %s: ошибка запроса в БД (новости с комментариями): %v", uniqueReqID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		err = rows.Scan(&id)
+		if err != nil {
+			log.Printf("request_id %s: ошибка чтения полученных данных из БД (новости с комментариями): %v", uniqueReqID, err)
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -1,23 +1,33 @@
 package api
 
 import (
+	"APIGateway/NewsAggregator/config"
+	"APIGateway/NewsAggregator/opml"
 	"APIGateway/NewsAggregator/storage"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
+	"expvar"
 	"log"
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 )
 
 type API struct {
-	db *storage.Storage
-	r  *mux.Router
+	db         *storage.Storage
+	cfg        *config.File
+	adminToken string
+	r          *mux.Router
 }
 
 // Конструктор API.
-func New(db *storage.Storage) *API {
-	a := API{db: db, r: mux.NewRouter()}
+func New(db *storage.Storage, cfg *config.File) *API {
+	a := API{db: db, cfg: cfg, adminToken: os.Getenv("ADMIN_TOKEN"), r: mux.NewRouter()}
 	a.endpoints()
 	return &a
 }
@@ -30,9 +40,88 @@ func (api *API) Router() *mux.Router {
 
 // Регистрация методов API в маршрутизаторе запросов.
 func (api *API) endpoints() {
-	api.r.HandleFunc("/newsList", api.newsList).Methods("GET")   // получение списка новостей
-	api.r.HandleFunc("/news", api.news).Methods("GET")           // получение новости по id
-	api.r.HandleFunc("/newsCheck", api.newsCheck).Methods("GET") // проверка наличия новости в БД
+	api.r.HandleFunc("/newsList", api.newsList).Methods("GET")           // получение списка новостей
+	api.r.HandleFunc("/news", api.news).Methods("GET")                   // получение новости по id
+	api.r.HandleFunc("/newsCheck", api.newsCheck).Methods("GET")         // проверка наличия новости в БД
+	api.r.HandleFunc("/newsRevisions", api.newsRevisions).Methods("GET") // прежние редакции новости
+	api.r.HandleFunc("/tags", api.tags).Methods("GET")                   // список тегов с числом новостей
+
+	api.r.HandleFunc("/admin/opml", api.admin(api.exportOPML)).Methods("GET")  // выгрузка списка лент в OPML
+	api.r.HandleFunc("/admin/opml", api.admin(api.importOPML)).Methods("POST") // импорт лент из OPML
+
+	// метрики сервиса (очистка новостей); содержат также командную строку и статистику памяти,
+	// поэтому доступны только администратору
+	api.r.HandleFunc("/debug/vars", api.admin(expvar.Handler().ServeHTTP)).Methods("GET")
+}
+
+// admin пропускает запрос к обработчику только с токеном администратора
+// в заголовке "Authorization: Bearer <токен>". Если переменная окружения
+// ADMIN_TOKEN не задана, административные методы отключены.
+func (api *API) admin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		uniqueReqID := r.URL.Query().Get("request_id")
+		if api.adminToken == "" {
+			log.Printf("request_id %s: административные методы отключены (не задан ADMIN_TOKEN)", uniqueReqID)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(api.adminToken)) != 1 {
+			log.Printf("request_id %s: неверный токен администратора", uniqueReqID)
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// выгрузка списка лент в OPML
+func (api *API) exportOPML(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/x-opml; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="feeds.opml"`)
+
+	uniqueReqID := r.URL.Query().Get("request_id")
+
+	err := opml.Write(w, "News Aggregator", api.cfg.Config().UrlList)
+	if err != nil {
+		log.Printf("request_id %s: ошибка выгрузки списка лент в OPML: %v", uniqueReqID, err)
+	}
+}
+
+// Ограничение размера загружаемого OPML-файла.
+const maxOPMLSize = 1 << 20
+
+// Результат импорта лент из OPML.
+type OPMLImport struct {
+	Total int      `json:"total"` // число лент в файле
+	Added []string `json:"added"` // добавленные в конфигурацию ленты
+}
+
+// импорт лент из OPML
+func (api *API) importOPML(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	uniqueReqID := r.URL.Query().Get("request_id")
+
+	urls, err := opml.Parse(http.MaxBytesReader(w, r.Body, maxOPMLSize))
+	if err != nil {
+		log.Printf("request_id %s: ошибка разбора OPML: %v", uniqueReqID, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	added, err := api.cfg.Merge(urls)
+	if err != nil {
+		log.Printf("request_id %s: ошибка записи файла конфигурации: %v", uniqueReqID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if added == nil {
+		added = []string{}
+	}
+	log.Printf("request_id %s: импорт OPML: лент в файле %d, добавлено %d", uniqueReqID, len(urls), len(added))
+	json.NewEncoder(w).Encode(OPMLImport{Total: len(urls), Added: added})
 }
 
 // получение списка новостей
@@ -60,7 +149,68 @@ func (api *API) newsList(w http.ResponseWriter, r *http.Request) {
 
 	search := r.URL.Query().Get("search")
 
-	news, err := api.db.NewsList(amount, page, search, uniqueReqID)
+	from, err := parseUnixParam(r.URL.Query().Get("from"))
+	if err != nil {
+		log.Printf("request_id %s: нижняя граница времени в url %s: %v", uniqueReqID, r.URL.Query().Get("from"), err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	to, err := parseUnixParam(r.URL.Query().Get("to"))
+	if err != nil {
+		log.Printf("request_id %s: верхняя граница времени в url %s: %v", uniqueReqID, r.URL.Query().Get("to"), err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	sort := r.URL.Query().Get("sort")
+	switch sort {
+	case "":
+		sort = storage.SortDesc
+	case storage.SortDesc, storage.SortAsc, storage.SortRelevance:
+	default:
+		log.Printf("request_id %s: неизвестный порядок сортировки в url %s", uniqueReqID, sort)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Режим курсора включается параметром paging=cursor или наличием курсора.
+	cursor := r.URL.Query().Get("cursor")
+	cursorMode := cursor != "" || r.URL.Query().Get("paging") == "cursor"
+
+	total := r.URL.Query().Get("total")
+	switch total {
+	case "":
+		total = storage.TotalExact
+		if cursorMode {
+			total = storage.TotalApprox
+		}
+	case storage.TotalExact, storage.TotalApprox, storage.TotalNone:
+	default:
+		log.Printf("request_id %s: неизвестный способ подсчёта новостей в url %s", uniqueReqID, total)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	filter := storage.NewsFilter{
+		Amount:     amount,
+		Page:       page,
+		Search:     search,
+		From:       from,
+		To:         to,
+		Sort:       sort,
+		Tag:        r.URL.Query().Get("tag"),
+		Source:     r.URL.Query().Get("source"),
+		Summary:    r.URL.Query().Get("summary") == "true",
+		CursorMode: cursorMode,
+		Cursor:     cursor,
+		Total:      total,
+	}
+	news, err := api.db.NewsList(filter, uniqueReqID)
+	if errors.Is(err, storage.ErrBadCursor) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		log.Printf("request_id %s: список новостей не получен из БД %v", uniqueReqID, err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -69,6 +219,64 @@ func (api *API) newsList(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(news)
 }
 
+// parseUnixParam разбирает время в формате Unix из параметра запроса.
+// Пустой параметр означает отсутствие ограничения (0).
+func parseUnixParam(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// список тегов с числом новостей за промежуток времени (по умолчанию - последние 7 дней)
+func (api *API) tags(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	uniqueReqID := r.URL.Query().Get("request_id")
+
+	to, err := parseUnixParam(r.URL.Query().Get("to"))
+	if err != nil {
+		log.Printf("request_id %s: верхняя граница времени в url %s: %v", uniqueReqID, r.URL.Query().Get("to"), err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if to == 0 {
+		to = time.Now().Unix()
+	}
+
+	from, err := parseUnixParam(r.URL.Query().Get("from"))
+	if err != nil {
+		log.Printf("request_id %s: нижняя граница времени в url %s: %v", uniqueReqID, r.URL.Query().Get("from"), err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if from == 0 {
+		from = to - int64(7*24*time.Hour/time.Second)
+	}
+
+	limit := 100
+	if limitSTR := r.URL.Query().Get("limit"); limitSTR != "" {
+		limit, err = strconv.Atoi(limitSTR)
+		if err != nil || limit <= 0 {
+			log.Printf("request_id %s: число тегов в url %s: %v", uniqueReqID, limitSTR, err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	tags, err := api.db.Tags(from, to, limit, uniqueReqID)
+	if err != nil {
+		log.Printf("request_id %s: список тегов не получен из БД: %v", uniqueReqID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if tags == nil {
+		tags = []storage.TagCount{}
+	}
+	json.NewEncoder(w).Encode(tags)
+}
+
 // получение новости по id
 func (api *API) news(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -84,12 +292,23 @@ func (api *API) news(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Формат содержания: html - очищенный HTML (по умолчанию), text - текст без разметки.
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "html" && format != "text" {
+		log.Printf("request_id %s: неизвестный формат содержания новости в url %s", uniqueReqID, format)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
 	news, err := api.db.News(news_id, uniqueReqID)
 	if err != nil {
 		log.Printf("request_id %s: новость %d не получена из БД: %v", uniqueReqID, news_id, err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if format == "text" {
+		news.Content = news.ContentText
+	}
 	if news.ID != 0 {
 		json.NewEncoder(w).Encode(news)
 	} else {
@@ -97,6 +316,44 @@ func (api *API) news(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// прежние редакции новости
+func (api *API) newsRevisions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	uniqueReqID := r.URL.Query().Get("request_id")
+
+	news_idSTR := r.URL.Query().Get("news_id")
+	news_id, err := strconv.Atoi(news_idSTR)
+	if err != nil {
+		log.Printf("request_id %s: id новости в url %s: %v", uniqueReqID, news_idSTR, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	check, err := api.db.NewsCheck(news_id, uniqueReqID)
+	if err != nil {
+		log.Printf("request_id %s: проверка наличия новости в БД; получена ошибка %v", uniqueReqID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !check {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	revisions, err := api.db.NewsRevisions(news_id, uniqueReqID)
+	if err != nil {
+		log.Printf("request_id %s: редакции новости %d не получены из БД: %v", uniqueReqID, news_id, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if revisions == nil {
+		revisions = []storage.Revision{}
+	}
+	json.NewEncoder(w).Encode(revisions)
+}
+
 // проверка наличия новости в БД
 func (api *API) newsCheck(w http.ResponseWriter, r *http.Request) {
 
@@ -110,15 +367,18 @@ func (api *API) newsCheck(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	check, err := api.db.NewsCheck(news_id, uniqueReqID)
+	// В ответе - источник новости: по нему выбирается политика проверки комментариев.
+	check, ok, err := api.db.NewsSource(news_id, uniqueReqID)
 
 	if err != nil {
 		log.Printf("request_id %s: проверка наличия новости в БД; получена ошибка %v", uniqueReqID, err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	if check {
+	if ok {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(check)
 	} else {
 		w.WriteHeader(http.StatusNotFound)
 	}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"APIGateway/NewsAggregator/config"
+	"APIGateway/NewsAggregator/opml"
+	"APIGateway/NewsAggregator/storage"
+	"APIGateway/pkg/migrate"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Справка по подкомандам.
+const usage = `использование:
+  news                          запуск сервиса
+  news opml import <файл.opml>  добавить ленты из OPML в config.json
+  news opml export [файл.opml]  выгрузить ленты из config.json в OPML (по умолчанию - в stdout)
+  news migrate up               применить миграции схемы БД
+  news migrate down [N]         отменить N последних миграций (по умолчанию - одну)
+  news migrate status           состояние миграций`
+
+// runCommand выполняет подкоманду командной строки.
+func runCommand(args []string) error {
+	if len(args) < 2 {
+		return errors.New(usage)
+	}
+	switch args[0] {
+	case "opml":
+		return opmlCommand(args[1:])
+	case "migrate":
+		return migrateCommand(args[1:])
+	default:
+		return errors.New(usage)
+	}
+}
+
+// opmlCommand выполняет подкоманды импорта и экспорта списка лент.
+func opmlCommand(args []string) error {
+	switch args[0] {
+	case "import":
+		if len(args) != 2 {
+			return errors.New(usage)
+		}
+		return importOPML(args[1])
+	case "export":
+		if len(args) > 2 {
+			return errors.New(usage)
+		}
+		var path string
+		if len(args) == 2 {
+			path = args[1]
+		}
+		return exportOPML(path)
+	default:
+		return errors.New(usage)
+	}
+}
+
+// importOPML добавляет в файл конфигурации ленты из OPML-файла.
+func importOPML(path string) error {
+	cfgFile, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("ошибка чтения файла конфигурации (%s):  %v", configPath, err)
+	}
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	urls, err := opml.Parse(in)
+	if err != nil {
+		return fmt.Errorf("ошибка разбора OPML (%s):  %v", path, err)
+	}
+	added, err := cfgFile.Merge(urls)
+	if err != nil {
+		return fmt.Errorf("ошибка записи файла конфигурации (%s):  %v", configPath, err)
+	}
+	fmt.Printf("лент в OPML: %d, добавлено: %d\n", len(urls), len(added))
+	for _, u := range added {
+		fmt.Println(" +", u)
+	}
+	return nil
+}
+
+// exportOPML выгружает список лент в OPML-файл или, если путь не задан, в stdout.
+func exportOPML(path string) error {
+	cfgFile, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("ошибка чтения файла конфигурации (%s):  %v", configPath, err)
+	}
+	var out io.Writer = os.Stdout
+	if path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	return opml.Write(out, "News Aggregator", cfgFile.Config().UrlList)
+}
+
+// migrateCommand применяет, отменяет миграции схемы БД или выводит их состояние.
+func migrateCommand(args []string) error {
+	err := migrate.Command(args, func() (migrate.Runner, error) {
+		return storage.New()
+	}, os.Stdout)
+	if errors.Is(err, migrate.ErrUsage) {
+		return errors.New(usage)
+	}
+	return err
+}
@@ -0,0 +1,125 @@
+// Пакет для работы с файлом конфигурации агрегатора.
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Структура конфигурационного файла.
+type Config struct {
+	UrlList   []string   `json:"rss"`                 // лист URL RSS каналаов
+	Period    int        `json:"request_period"`      // период опроса
+	Retention *Retention `json:"retention,omitempty"` // очистка старых новостей; nil - отключена
+}
+
+// Способы архивирования новостей перед удалением.
+const (
+	ArchiveTable = "table" // таблица news_archive в БД
+	ArchiveFile  = "file"  // сжатый gzip файл JSONL в каталоге ArchiveDir
+	ArchiveNone  = "none"  // удаление без архивирования
+)
+
+// Политика хранения новостей.
+type Retention struct {
+	MaxAgeDays   int    `json:"max_age_days"`          // удалять новости старше указанного числа дней; 0 - без ограничения
+	MaxPerSource int    `json:"max_per_source"`        // хранить не более указанного числа новостей источника; 0 - без ограничения
+	Interval     int    `json:"interval"`              // период очистки в минутах
+	Archive      string `json:"archive"`               // способ архивирования: table, file, none
+	ArchiveDir   string `json:"archive_dir,omitempty"` // каталог файлов архива (для archive = file)
+	Force        bool   `json:"force,omitempty"`       // удалять и новости с комментариями
+}
+
+// Конфигурация, связанная с файлом. Список лент можно дополнять во время работы,
+// изменения сохраняются в файл.
+type File struct {
+	mu    sync.Mutex
+	path  string
+	cfg   Config
+	onAdd func(url string) // вызывается для каждой добавленной ленты
+}
+
+// Load читает файл конфигурации.
+func Load(path string) (*File, error) {
+	fileIn, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f := File{path: path}
+	err = json.Unmarshal(fileIn, &f.cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// Config возвращает копию текущей конфигурации.
+func (f *File) Config() Config {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	cfg := f.cfg
+	cfg.UrlList = append([]string(nil), f.cfg.UrlList...)
+	return cfg
+}
+
+// OnAdd задаёт функцию, вызываемую для каждой ленты, добавленной через Merge.
+func (f *File) OnAdd(fn func(url string)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.onAdd = fn
+}
+
+// Merge добавляет в список лент отсутствующие в нём адреса и сохраняет файл.
+// Возвращает добавленные адреса.
+func (f *File) Merge(urls []string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	known := make(map[string]bool, len(f.cfg.UrlList))
+	for _, u := range f.cfg.UrlList {
+		known[u] = true
+	}
+	var added []string
+	for _, u := range urls {
+		u = strings.TrimSpace(u)
+		if u == "" || known[u] {
+			continue
+		}
+		known[u] = true
+		added = append(added, u)
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+
+	cfg := f.cfg
+	cfg.UrlList = append(append([]string(nil), f.cfg.UrlList...), added...)
+	err := save(f.path, cfg)
+	if err != nil {
+		return nil, err
+	}
+	f.cfg = cfg
+	if f.onAdd != nil {
+		for _, u := range added {
+			f.onAdd(u)
+		}
+	}
+	return added, nil
+}
+
+// save записывает конфигурацию в файл через временный файл,
+// чтобы при сбое не повредить исходный.
+func save(path string, cfg Config) error {
+	b, err := json.MarshalIndent(cfg, "", "    ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	err = os.WriteFile(tmp, append(b, '\n'), 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
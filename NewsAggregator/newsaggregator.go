@@ -2,28 +2,35 @@ package main
 
 import (
 	"APIGateway/NewsAggregator/api"
+	"APIGateway/NewsAggregator/config"
+	"APIGateway/NewsAggregator/retention"
 	"APIGateway/NewsAggregator/rss"
 	"APIGateway/NewsAggregator/storage"
-	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"time"
-
-	"github.com/jackc/pgconn"
 )
 
-// Структура конфигурационного файла.
-type config struct {
-	UrlList []string `json:"rss"`            // лист URL RSS каналаов
-	Period  int      `json:"request_period"` // период опроса
-}
+// Файл конфигурации со списком RSS URLs и периодом опроса.
+const configPath = "./config.json"
 
 var port = os.Getenv("API_PORT")
+var autoMigrate = os.Getenv("DB_AUTO_MIGRATE") != "false"
 
 func main() {
 
+	// Подкоманды командной строки (импорт и экспорт списка лент).
+	if len(os.Args) > 1 {
+		err := runCommand(os.Args[1:])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Создаём канал для публикаций.
 	chPosts := make(chan []storage.NewsFullDetailed)
 
@@ -38,43 +45,70 @@ func main() {
 	}()
 
 	// Читаем файл конфигурации со списком RSS URLs и периодом опроса.
-	fileIn, err := os.ReadFile("./config.json")
-	if err != nil {
-		chErrs <- fmt.Errorf("ошибка чтения файла конфигурации (config.json):  %v", err)
-	}
-
-	// Формируем структуру конфигурации из считанного файла конфигурации.
-	var config config
-	err = json.Unmarshal(fileIn, &config)
+	cfgFile, err := config.Load(configPath)
 	if err != nil {
-		chErrs <- fmt.Errorf("ошибка демаршалинга файла конфигурации (config.json):  %v", err)
+		log.Fatalf("ошибка чтения файла конфигурации (%s):  %v", configPath, err)
 	}
+	cfg := cfgFile.Config()
 
 	// Реляционная БД PostgreSQL.
 	db, err := storage.New()
 	if err != nil {
-		chErrs <- fmt.Errorf("ошибка подключения к БД:  %v", err)
+		log.Fatalf("ошибка подключения к БД:  %v", err)
 	}
 
-	api := api.New(db)
+	// Миграции схемы БД при запуске (отключаются переменной окружения DB_AUTO_MIGRATE=false).
+	if autoMigrate {
+		_, err = db.MigrateUp()
+		if err != nil {
+			log.Fatalf("ошибка миграции схемы БД:  %v", err)
+		}
+	}
+
+	api := api.New(db, cfgFile)
 
 	// Проходим по списку RSS ссылок.
 	// Для каждого RSS-канала запускается своя горутина.
-	for _, url := range config.UrlList {
-		go parseURL(url, chPosts, chErrs, config.Period)
+	for _, url := range cfg.UrlList {
+		go parseURL(url, chPosts, chErrs, cfg.Period)
+	}
+	// Ленты, импортированные во время работы, опрашиваются так же.
+	cfgFile.OnAdd(func(url string) {
+		go parseURL(url, chPosts, chErrs, cfg.Period)
+	})
+
+	// Очистка старых новостей по политике хранения из файла конфигурации.
+	if cfg.Retention != nil {
+		job, err := retention.New(db, *cfg.Retention)
+		if err != nil {
+			log.Fatalf("ошибка в политике хранения новостей (%s):  %v", configPath, err)
+		}
+		go job.Run(chErrs)
 	}
 
 	// запись потока новостей в БД
 	go func() {
 		for posts := range chPosts {
-			err := db.AddNews(posts)
-			// Исключаем логирование ожидаемой ошибки записи дубликата новости в БД
-			// "ERROR: duplicate key value violates unique constraint \"news_link_key\" (SQLSTATE 23505)"
-			// в соответствии с правилом schema.sql
-			// link TEXT NOT NULL UNIQUE -- UNIQUE для link позволяет избежать дублирования новостей в БД.
-			if err != nil && err.(*pgconn.PgError).Code != "23505" {
-				chErrs <- fmt.Errorf("ошибка при добавлении новости в БД:  %v", err)
+			// Дубликаты не являются ошибкой: они пропускаются и учитываются в skipped.
+			results, err := db.AddNews(posts)
+			if err != nil {
+				chErrs <- fmt.Errorf("ошибка при добавлении новостей в БД:  %v", err)
+				continue
+			}
+			var inserted, updated, skipped int
+			for _, res := range results {
+				switch {
+				case res.Err != nil:
+					chErrs <- fmt.Errorf("новость %s не добавлена в БД:  %v", res.Link, res.Err)
+				case res.Inserted:
+					inserted++
+				case res.Updated:
+					updated++
+				default:
+					skipped++
+				}
 			}
+			log.Printf("новости записаны в БД: добавлено %d, обновлено %d, пропущено дубликатов %d", inserted, updated, skipped)
 		}
 	}()
 
@@ -0,0 +1,77 @@
+// Пакет для импорта и экспорта списка RSS-лент в формате OPML.
+package opml
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"time"
+)
+
+type OPML struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    Head     `xml:"head"`
+	Body    Body     `xml:"body"`
+}
+
+type Head struct {
+	Title       string `xml:"title"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+type Body struct {
+	Outlines []Outline `xml:"outline"`
+}
+
+// Элемент списка: лента (с xmlUrl) или папка с вложенными элементами.
+type Outline struct {
+	Text     string    `xml:"text,attr"`
+	Title    string    `xml:"title,attr,omitempty"`
+	Type     string    `xml:"type,attr,omitempty"`
+	XMLURL   string    `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string    `xml:"htmlUrl,attr,omitempty"`
+	Outlines []Outline `xml:"outline"`
+}
+
+// Parse читает OPML-документ и возвращает адреса всех лент, включая ленты во вложенных папках.
+func Parse(r io.Reader) ([]string, error) {
+	var doc OPML
+	err := xml.NewDecoder(r).Decode(&doc)
+	if err != nil {
+		return nil, err
+	}
+	var urls []string
+	var walk func(outlines []Outline)
+	walk = func(outlines []Outline) {
+		for _, o := range outlines {
+			if u := strings.TrimSpace(o.XMLURL); u != "" {
+				urls = append(urls, u)
+			}
+			walk(o.Outlines)
+		}
+	}
+	walk(doc.Body.Outlines)
+	return urls, nil
+}
+
+// Write записывает список лент в формате OPML 2.0.
+func Write(w io.Writer, title string, urls []string) error {
+	doc := OPML{
+		Version: "2.0",
+		Head: Head{
+			Title:       title,
+			DateCreated: time.Now().UTC().Format(time.RFC1123Z),
+		},
+	}
+	for _, u := range urls {
+		doc.Body.Outlines = append(doc.Body.Outlines, Outline{Text: u, Type: "rss", XMLURL: u})
+	}
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(doc)
+}
@@ -0,0 +1,246 @@
+// Пакет для периодической очистки БД от старых новостей с архивированием.
+package retention
+
+import (
+	"APIGateway/NewsAggregator/config"
+	"APIGateway/NewsAggregator/storage"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Сервис комментариев: отбор новостей, к которым есть комментарии.
+const commentedNewsURL = "http://comments:8082/commentedNews"
+
+// Число новостей, обрабатываемых за один шаг очистки.
+const batchSize = 500
+
+// Период очистки по умолчанию (в минутах).
+const defaultInterval = 60
+
+// Метрики очистки, доступные по /debug/vars.
+var (
+	metrics          = expvar.NewMap("retention")
+	metricRuns       = new(expvar.Int) // число запусков очистки
+	metricArchived   = new(expvar.Int) // число новостей, перенесённых в архив
+	metricDeleted    = new(expvar.Int) // число удалённых новостей (в т.ч. без архивирования)
+	metricLastKept   = new(expvar.Int) // число новостей, оставленных из-за комментариев при последнем запуске
+	metricErrors     = new(expvar.Int) // число запусков, завершившихся ошибкой
+	metricLastRun    = new(expvar.Int) // время последнего запуска (Unix)
+	metricLastPurged = new(expvar.Int) // число новостей, удалённых при последнем запуске
+)
+
+func init() {
+	metrics.Set("runs", metricRuns)
+	metrics.Set("archived_total", metricArchived)
+	metrics.Set("deleted_total", metricDeleted)
+	metrics.Set("errors_total", metricErrors)
+	metrics.Set("last_run", metricLastRun)
+	metrics.Set("last_run_deleted", metricLastPurged)
+	metrics.Set("last_run_kept_commented", metricLastKept)
+}
+
+// Фоновая очистка новостей по политике хранения.
+type Job struct {
+	db     *storage.Storage
+	policy config.Retention
+}
+
+// Конструктор задачи очистки. Проверяет политику и задаёт значения по умолчанию.
+func New(db *storage.Storage, policy config.Retention) (*Job, error) {
+	if policy.MaxAgeDays < 0 || policy.MaxPerSource < 0 || policy.Interval < 0 {
+		return nil, fmt.Errorf("отрицательное значение в политике хранения новостей")
+	}
+	if policy.MaxAgeDays == 0 && policy.MaxPerSource == 0 {
+		return nil, fmt.Errorf("в политике хранения новостей не задано ни max_age_days, ни max_per_source")
+	}
+	if policy.Interval == 0 {
+		policy.Interval = defaultInterval
+	}
+	switch policy.Archive {
+	case "":
+		policy.Archive = config.ArchiveTable
+	case config.ArchiveTable, config.ArchiveNone:
+	case config.ArchiveFile:
+		if policy.ArchiveDir == "" {
+			return nil, fmt.Errorf("для архивирования в файл не задан archive_dir")
+		}
+	default:
+		return nil, fmt.Errorf("неизвестный способ архивирования новостей %q", policy.Archive)
+	}
+	return &Job{db: db, policy: policy}, nil
+}
+
+// Run запускает очистку с периодом из политики. Ошибки пишутся в канал.
+func (j *Job) Run(errs chan<- error) {
+	for {
+		err := j.Purge()
+		if err != nil {
+			errs <- fmt.Errorf("очистка старых новостей:  %v", err)
+		}
+		time.Sleep(time.Minute * time.Duration(j.policy.Interval))
+	}
+}
+
+// Purge архивирует и удаляет новости, вышедшие за пределы политики хранения.
+// Новости с комментариями остаются в БД, если в политике не задан force.
+// Если сервис комментариев недоступен, очистка без force не выполняется.
+func (j *Job) Purge() (err error) {
+	metricRuns.Add(1)
+	metricLastRun.Set(time.Now().Unix())
+	var purged int64
+	var kept []int // новости с комментариями исключаются из последующих выборок
+	defer func() {
+		metricLastPurged.Set(purged)
+		metricLastKept.Set(int64(len(kept)))
+		if err != nil {
+			metricErrors.Add(1)
+		}
+	}()
+
+	var before int64
+	if j.policy.MaxAgeDays > 0 {
+		before = time.Now().AddDate(0, 0, -j.policy.MaxAgeDays).Unix()
+	}
+
+	for {
+		ids, err := j.db.RetentionCandidates(before, j.policy.MaxPerSource, batchSize, kept)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		if !j.policy.Force {
+			commented, err := commentedNews(ids)
+			if err != nil {
+				return fmt.Errorf("не удалось проверить наличие комментариев к новостям: %v", err)
+			}
+			if len(commented) > 0 {
+				kept = append(kept, commented...)
+				ids = exclude(ids, commented)
+			}
+		}
+		if len(ids) == 0 {
+			continue
+		}
+
+		n, err := j.purge(ids)
+		purged += n
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// purge архивирует новости выбранным способом и удаляет их. При архивировании в таблицу
+// перенос и удаление выполняются одним запросом. При архивировании в файл новости удаляются
+// после записи файла; если удаление не удалось, при следующем запуске они попадут в архив повторно.
+func (j *Job) purge(ids []int) (int64, error) {
+	switch j.policy.Archive {
+	case config.ArchiveTable:
+		n, err := j.db.ArchiveToTable(ids, time.Now().Unix())
+		metricArchived.Add(n)
+		metricDeleted.Add(n)
+		return n, err
+	case config.ArchiveFile:
+		data, err := j.db.ArchiveData(ids)
+		if err != nil {
+			return 0, err
+		}
+		err = writeArchive(j.policy.ArchiveDir, data)
+		if err != nil {
+			return 0, err
+		}
+		metricArchived.Add(int64(len(data)))
+		n, err := j.db.DeleteNews(ids)
+		metricDeleted.Add(n)
+		return n, err
+	default:
+		n, err := j.db.DeleteNews(ids)
+		metricDeleted.Add(n)
+		return n, err
+	}
+}
+
+// writeArchive записывает новости в новый файл архива: по одной новости в формате JSON
+// на строку, сжатие gzip. Файл создаётся под временным именем и переименовывается
+// только после успешной записи, чтобы в каталоге не оставалось неполных архивов.
+func writeArchive(dir string, data [][]byte) error {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+	name := filepath.Join(dir, fmt.Sprintf("news-%s.jsonl.gz", time.Now().UTC().Format("20060102-150405.000000000")))
+
+	f, err := os.Create(name + ".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	zw := gzip.NewWriter(f)
+	for _, b := range data {
+		_, err = zw.Write(append(b, '\n'))
+		if err != nil {
+			return err
+		}
+	}
+	err = zw.Close()
+	if err != nil {
+		return err
+	}
+	err = f.Sync()
+	if err != nil {
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
+
+// commentedNews запрашивает у сервиса комментариев, к каким из новостей есть комментарии.
+func commentedNews(ids []int) ([]int, error) {
+	body, err := json.Marshal(ids)
+	if err != nil {
+		return nil, err
+	}
+	client := http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Post(commentedNewsURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ответ от comments (status code): %d", resp.StatusCode)
+	}
+	var commented []int
+	err = json.NewDecoder(resp.Body).Decode(&commented)
+	return commented, err
+}
+
+// exclude возвращает ids без элементов из remove.
+func exclude(ids, remove []int) []int {
+	skip := make(map[int]bool, len(remove))
+	for _, id := range remove {
+		skip[id] = true
+	}
+	var out []int
+	for _, id := range ids {
+		if !skip[id] {
+			out = append(out, id)
+		}
+	}
+	return out
+}
@@ -0,0 +1,56 @@
+package rss
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// Атрибут encoding в XML-декларации.
+var xmlEncodingDecl = regexp.MustCompile(`^(<\?xml[^>]*?encoding\s*=\s*)["'][^"']*["']`)
+
+// toUTF8 перекодирует ленту в UTF-8 согласно кодировке из заголовка Content-Type.
+// Кодировка из заголовка имеет приоритет над XML-декларацией (RFC 7303), поэтому
+// после перекодирования декларация исправляется на UTF-8. Если в заголовке
+// кодировка не указана, лента возвращается без изменений: её кодировку
+// по XML-декларации определит newDecoder.
+func toUTF8(body []byte, contentType string) ([]byte, error) {
+	body = bytes.TrimPrefix(body, []byte("\xef\xbb\xbf"))
+
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil || params["charset"] == "" {
+		return body, nil
+	}
+	label := strings.ToLower(strings.TrimSpace(params["charset"]))
+	if label == "utf-8" || label == "utf8" {
+		return setUTF8Decl(body), nil
+	}
+	enc, err := htmlindex.Get(label)
+	if err != nil {
+		return nil, fmt.Errorf("неизвестная кодировка ленты %q: %v", label, err)
+	}
+	out, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка перекодирования ленты из %s: %v", label, err)
+	}
+	return setUTF8Decl(out), nil
+}
+
+// setUTF8Decl заменяет кодировку в XML-декларации на UTF-8.
+func setUTF8Decl(body []byte) []byte {
+	return xmlEncodingDecl.ReplaceAll(body, []byte(`${1}"UTF-8"`))
+}
+
+// newDecoder возвращает XML-декодер, перекодирующий документ в UTF-8
+// согласно кодировке из XML-декларации (windows-1251, KOI8-R и т.д.).
+func newDecoder(body []byte) *xml.Decoder {
+	d := xml.NewDecoder(bytes.NewReader(body))
+	d.CharsetReader = charset.NewReaderLabel
+	return d
+}
@@ -0,0 +1,124 @@
+package rss
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Заголовок и содержание новости во всех лентах из testdata.
+const (
+	fixtureTitle   = "Новости дня: съешь ещё"
+	fixtureContent = "Этих мягких французских булок, да выпей же чаю"
+)
+
+func Test_toUTF8(t *testing.T) {
+	tests := []struct {
+		name        string
+		file        string
+		contentType string
+	}{
+		{
+			name:        "кодировка только в заголовке, windows-1251",
+			file:        "windows-1251-nodecl.xml",
+			contentType: "application/rss+xml; charset=windows-1251",
+		},
+		{
+			name:        "кодировка только в XML-декларации, windows-1251",
+			file:        "windows-1251.xml",
+			contentType: "application/rss+xml",
+		},
+		{
+			name:        "кодировка только в XML-декларации, KOI8-R",
+			file:        "koi8-r.xml",
+			contentType: "text/xml",
+		},
+		{
+			name:        "заголовок и декларация совпадают, KOI8-R",
+			file:        "koi8-r.xml",
+			contentType: `text/xml; charset="KOI8-R"`,
+		},
+		{
+			name:        "заголовок и декларация расходятся, приоритет у заголовка",
+			file:        "koi8-r-decl-mismatch.xml",
+			contentType: "text/xml; charset=koi8-r",
+		},
+		{
+			name:        "UTF-8 с BOM без кодировки в заголовке",
+			file:        "utf-8-bom.xml",
+			contentType: "application/rss+xml",
+		},
+		{
+			name:        "UTF-8 с BOM и кодировкой в заголовке",
+			file:        "utf-8-bom.xml",
+			contentType: "application/rss+xml; charset=UTF-8",
+		},
+		{
+			name:        "без заголовка Content-Type",
+			file:        "windows-1251.xml",
+			contentType: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, err = toUTF8(body, tt.contentType)
+			if err != nil {
+				t.Fatalf("toUTF8() error = %v", err)
+			}
+			if bytes.HasPrefix(body, []byte("\xef\xbb\xbf")) {
+				t.Errorf("toUTF8() не удалил BOM")
+			}
+			root, err := rootElement(body)
+			if err != nil {
+				t.Fatalf("rootElement() error = %v", err)
+			}
+			if root != "rss" {
+				t.Fatalf("rootElement() = %q, want %q", root, "rss")
+			}
+			news, err := parseRSS(body, "example.com", time.Now())
+			if err != nil {
+				t.Fatalf("parseRSS() error = %v", err)
+			}
+			if len(news) != 1 {
+				t.Fatalf("parseRSS() вернул %d новостей, want 1", len(news))
+			}
+			if news[0].Title != fixtureTitle {
+				t.Errorf("Title = %q, want %q", news[0].Title, fixtureTitle)
+			}
+			if news[0].ContentText != fixtureContent {
+				t.Errorf("ContentText = %q, want %q", news[0].ContentText, fixtureContent)
+			}
+		})
+	}
+}
+
+func Test_toUTF8_declaration(t *testing.T) {
+	body, err := os.ReadFile(filepath.Join("testdata", "koi8-r-decl-mismatch.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err = toUTF8(body, "text/xml; charset=koi8-r")
+	if err != nil {
+		t.Fatalf("toUTF8() error = %v", err)
+	}
+	want := []byte(`<?xml version="1.0" encoding="UTF-8"?>`)
+	if !bytes.HasPrefix(body, want) {
+		t.Errorf("XML-декларация = %q, want %q", body[:len(want)], want)
+	}
+}
+
+func Test_toUTF8_unknownCharset(t *testing.T) {
+	body, err := os.ReadFile(filepath.Join("testdata", "windows-1251.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = toUTF8(body, "text/xml; charset=x-unknown"); err == nil {
+		t.Error("toUTF8() error = nil, want ошибку неизвестной кодировки")
+	}
+}
@@ -0,0 +1,114 @@
+package rss
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Смещения именованных часовых поясов (в часах), встречающихся в лентах.
+// Go распознаёт такие обозначения только для локального пояса,
+// поэтому перед разбором они заменяются числовым смещением.
+var zoneOffsets = map[string]float64{
+	"UT": 0, "UTC": 0, "GMT": 0, "Z": 0, "WET": 0,
+	"EST": -5, "EDT": -4, "CST": -6, "CDT": -5,
+	"MST": -7, "MDT": -6, "PST": -8, "PDT": -7,
+	"BST": 1, "CET": 1, "WEST": 1, "CEST": 2, "EET": 2, "EEST": 3,
+	"MSK": 3, "MSD": 4, "SAMT": 4, "YEKT": 5, "OMST": 6, "KRAT": 7,
+	"IRKT": 8, "YAKT": 9, "VLAT": 10, "MAGT": 11, "PETT": 12,
+	"IST": 5.5, "JST": 9, "AEST": 10, "AEDT": 11,
+}
+
+// Форматы даты RFC 822/1123 и их распространённые варианты:
+// с днём недели и без, с секундами и без, с двух- и четырёхзначным годом.
+var rfc822Layouts = func() []string {
+	var layouts []string
+	for _, weekday := range []string{"Mon, ", "Monday, ", "Mon ", ""} {
+		for _, year := range []string{"2006", "06"} {
+			for _, clock := range []string{"15:04:05", "15:04"} {
+				for _, zone := range []string{" -0700", " -07:00", ""} {
+					layouts = append(layouts,
+						weekday+"2 Jan "+year+" "+clock+zone,
+						weekday+"2 January "+year+" "+clock+zone,
+					)
+				}
+			}
+		}
+	}
+	return layouts
+}()
+
+// Форматы ISO 8601 (в т.ч. RFC 3339, используемый в Atom).
+var isoLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// Насколько дата публикации может опережать время получения ленты
+// (расхождение часов источника). Более поздние даты считаются ошибочными.
+const maxFutureSkew = 48 * time.Hour
+
+// parseDate разбирает дату публикации новости. Если дата отсутствует,
+// не распознана или лежит далеко в будущем, возвращается время получения ленты
+// fetched и признак estimated.
+func parseDate(s string, fetched time.Time) (t time.Time, estimated bool) {
+	t, err := parseDateString(s)
+	if err != nil || t.After(fetched.Add(maxFutureSkew)) {
+		return fetched, true
+	}
+	return t, false
+}
+
+// parseDateString разбирает дату в одном из форматов RFC 822 или ISO 8601.
+// Дата без часового пояса считается заданной в UTC.
+func parseDateString(s string) (time.Time, error) {
+	s = strings.Join(strings.Fields(s), " ")
+	if s == "" {
+		return time.Time{}, fmt.Errorf("пустая дата")
+	}
+
+	for _, layout := range isoLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+
+	s = numericZone(s)
+	for _, layout := range rfc822Layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("неизвестный формат даты %q", s)
+}
+
+// numericZone заменяет именованный часовой пояс в конце даты числовым смещением.
+// Также обрабатываются записи вида "GMT+0300" и "UTC+03:00".
+func numericZone(s string) string {
+	i := strings.LastIndex(s, " ")
+	if i < 0 {
+		return s
+	}
+	date, zone := s[:i], strings.ToUpper(s[i+1:])
+	for _, prefix := range []string{"GMT", "UTC", "UT"} {
+		if rest := strings.TrimPrefix(zone, prefix); rest != zone && (strings.HasPrefix(rest, "+") || strings.HasPrefix(rest, "-")) {
+			return date + " " + rest
+		}
+	}
+	offset, ok := zoneOffsets[zone]
+	if !ok {
+		return s
+	}
+	sign := "+"
+	if offset < 0 {
+		sign, offset = "-", -offset
+	}
+	minutes := int(offset * 60)
+	return fmt.Sprintf("%s %s%02d%02d", date, sign, minutes/60, minutes%60)
+}
@@ -0,0 +1,98 @@
+package rss
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_parseDateString(t *testing.T) {
+	msk := time.FixedZone("", 3*60*60)
+	est := time.FixedZone("", -5*60*60)
+	ist := time.FixedZone("", 5*60*60+30*60)
+	tests := []struct {
+		name string
+		s    string
+		want time.Time
+	}{
+		// RFC 822/1123 и варианты
+		{name: "RFC 1123 с числовым поясом", s: "Mon, 02 Jan 2006 15:04:05 +0300", want: time.Date(2006, 1, 2, 15, 4, 5, 0, msk)},
+		{name: "RFC 1123 GMT", s: "Mon, 02 Jan 2006 15:04:05 GMT", want: time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{name: "пояс MSK", s: "Mon, 02 Jan 2006 15:04:05 MSK", want: time.Date(2006, 1, 2, 15, 4, 5, 0, msk)},
+		{name: "пояс EST", s: "Tue, 10 Jun 2003 04:00:00 EST", want: time.Date(2003, 6, 10, 4, 0, 0, 0, est)},
+		{name: "пояс IST с получасовым смещением", s: "Mon, 02 Jan 2006 15:04:05 IST", want: time.Date(2006, 1, 2, 15, 4, 5, 0, ist)},
+		{name: "пояс в нижнем регистре", s: "Mon, 02 Jan 2006 15:04:05 msk", want: time.Date(2006, 1, 2, 15, 4, 5, 0, msk)},
+		{name: "GMT+0300", s: "Mon, 02 Jan 2006 15:04:05 GMT+0300", want: time.Date(2006, 1, 2, 15, 4, 5, 0, msk)},
+		{name: "UTC+03:00", s: "Mon, 02 Jan 2006 15:04:05 UTC+03:00", want: time.Date(2006, 1, 2, 15, 4, 5, 0, msk)},
+		{name: "смещение с двоеточием", s: "Mon, 02 Jan 2006 15:04:05 +03:00", want: time.Date(2006, 1, 2, 15, 4, 5, 0, msk)},
+		{name: "двузначный год", s: "Sat, 07 Sep 02 00:00:01 GMT", want: time.Date(2002, 9, 7, 0, 0, 1, 0, time.UTC)},
+		{name: "двузначный год 06 с поясом MSK", s: "Mon, 02 Jan 06 15:04:05 MSK", want: time.Date(2006, 1, 2, 15, 4, 5, 0, msk)},
+		{name: "без дня недели и секунд", s: "7 Sep 2002 00:00 +0300", want: time.Date(2002, 9, 7, 0, 0, 0, 0, msk)},
+		{name: "полные названия дня и месяца", s: "Monday, 02 January 2006 15:04:05 EST", want: time.Date(2006, 1, 2, 15, 4, 5, 0, est)},
+		{name: "день недели без запятой", s: "Mon 02 Jan 2006 15:04:05 +0000", want: time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{name: "без пояса считается UTC", s: "Mon, 02 Jan 2006 15:04:05", want: time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{name: "лишние пробелы", s: "  Mon,  02 Jan 2006   15:04:05  +0300 ", want: time.Date(2006, 1, 2, 15, 4, 5, 0, msk)},
+		// ISO 8601
+		{name: "RFC 3339 Z", s: "2006-01-02T15:04:05Z", want: time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{name: "RFC 3339 с долями секунды", s: "2006-01-02T15:04:05.123+03:00", want: time.Date(2006, 1, 2, 15, 4, 5, 123000000, msk)},
+		{name: "ISO 8601 смещение без двоеточия", s: "2006-01-02T15:04:05+0300", want: time.Date(2006, 1, 2, 15, 4, 5, 0, msk)},
+		{name: "ISO 8601 без секунд", s: "2006-01-02T15:04-05:00", want: time.Date(2006, 1, 2, 15, 4, 0, 0, est)},
+		{name: "ISO 8601 без пояса", s: "2006-01-02T15:04:05", want: time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{name: "ISO 8601 через пробел", s: "2006-01-02 15:04:05", want: time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{name: "только дата", s: "2006-01-02", want: time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDateString(tt.s)
+			if err != nil {
+				t.Fatalf("parseDateString(%q) error = %v", tt.s, err)
+			}
+			// сравниваются и момент времени, и смещение пояса
+			if got.Format(time.RFC3339Nano) != tt.want.Format(time.RFC3339Nano) {
+				t.Errorf("parseDateString(%q) = %v, want %v", tt.s, got.Format(time.RFC3339Nano), tt.want.Format(time.RFC3339Nano))
+			}
+		})
+	}
+}
+
+func Test_parseDateString_error(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"   ",
+		"вчера",
+		"not a date",
+		"32 Jan 2006 10:00 +0000",
+		"Mon, 02 Foo 2006 15:04:05 GMT",
+		"Mon, 02 Jan 2006 15:04:05 XYZ",
+		"2006-13-01",
+	} {
+		if got, err := parseDateString(s); err == nil {
+			t.Errorf("parseDateString(%q) = %v, want ошибку", s, got)
+		}
+	}
+}
+
+func Test_parseDate(t *testing.T) {
+	fetched := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		s             string
+		want          time.Time
+		wantEstimated bool
+	}{
+		{name: "дата в прошлом", s: "Fri, 01 Mar 2024 09:00:00 MSK", want: time.Date(2024, 3, 1, 6, 0, 0, 0, time.UTC)},
+		{name: "дата в будущем в пределах расхождения часов", s: "2024-03-03T11:00:00Z", want: time.Date(2024, 3, 3, 11, 0, 0, 0, time.UTC)},
+		{name: "дата ровно на границе расхождения", s: "2024-03-03T12:00:00Z", want: time.Date(2024, 3, 3, 12, 0, 0, 0, time.UTC)},
+		{name: "дата дальше 48 часов в будущем", s: "2024-03-03T12:00:01Z", want: fetched, wantEstimated: true},
+		{name: "дата через год", s: "Sat, 01 Mar 2025 12:00:00 GMT", want: fetched, wantEstimated: true},
+		{name: "нет даты", s: "", want: fetched, wantEstimated: true},
+		{name: "нераспознанная дата", s: "вчера в 12:00", want: fetched, wantEstimated: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, estimated := parseDate(tt.s, fetched)
+			if !got.Equal(tt.want) || estimated != tt.wantEstimated {
+				t.Errorf("parseDate(%q) = %v, %v, want %v, %v", tt.s, got, estimated, tt.want, tt.wantEstimated)
+			}
+		})
+	}
+}
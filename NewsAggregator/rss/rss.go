@@ -2,10 +2,14 @@
 package rss
 
 import (
+	"APIGateway/NewsAggregator/sanitize"
 	"APIGateway/NewsAggregator/storage"
 	"encoding/xml"
+	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 )
 
@@ -22,27 +26,139 @@ type Channel struct {
 }
 
 type Item struct {
-	Title       string `xml:"title"`
-	Description string `xml:"description"`
-	PubDate     string `xml:"pubDate"`
-	Link        string `xml:"link"`
+	Title       string   `xml:"title"`
+	Description string   `xml:"description"`
+	PubDate     string   `xml:"pubDate"`
+	Link        string   `xml:"link"`
+	GUID        string   `xml:"guid"`
+	Categories  []string `xml:"category"`
+
+	Enclosures      []Enclosure      `xml:"enclosure"`
+	MediaContents   []MediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+	MediaThumbnails []MediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	MediaGroups     []MediaGroup     `xml:"http://search.yahoo.com/mrss/ group"`
+}
+
+// Вложение RSS (<enclosure>).
+type Enclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length int64  `xml:"length,attr"`
+}
+
+// Элемент Media RSS (<media:content>).
+type MediaContent struct {
+	URL        string           `xml:"url,attr"`
+	Type       string           `xml:"type,attr"`
+	Medium     string           `xml:"medium,attr"`
+	FileSize   int64            `xml:"fileSize,attr"`
+	Width      int              `xml:"width,attr"`
+	Height     int              `xml:"height,attr"`
+	Thumbnails []MediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+}
+
+// Миниатюра Media RSS (<media:thumbnail>).
+type MediaThumbnail struct {
+	URL    string `xml:"url,attr"`
+	Width  int    `xml:"width,attr"`
+	Height int    `xml:"height,attr"`
+}
+
+// Группа вариантов одного медиафайла Media RSS (<media:group>).
+type MediaGroup struct {
+	Contents   []MediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+	Thumbnails []MediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+}
+
+// Лента в формате Atom.
+type AtomFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	Title   string      `xml:"title"`
+	Entries []AtomEntry `xml:"entry"`
+}
+
+type AtomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Summary    string         `xml:"summary"`
+	Content    string         `xml:"content"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Links      []AtomLink     `xml:"link"`
+	Categories []AtomCategory `xml:"category"`
+}
+
+type AtomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr"`
+}
+
+type AtomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr"`
+	Type   string `xml:"type,attr"`
+	Length int64  `xml:"length,attr"`
 }
 
 // Получаем и обрабатываем данные из RSS канала.
-func ReadRSS(url string) ([]storage.NewsFullDetailed, error) {
+func ReadRSS(feedURL string) ([]storage.NewsFullDetailed, error) {
 
-	res, err := http.Get(url)
+	fetched := time.Now()
+	res, err := http.Get(feedURL)
 	if err != nil {
 		return nil, err
 	}
+	defer res.Body.Close()
 
 	body, err := io.ReadAll(res.Body)
 	if err != nil {
 		return nil, err
 	}
 
+	body, err = toUTF8(body, res.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Источник новостей - имя хоста ленты.
+	var source string
+	if u, err := url.Parse(feedURL); err == nil {
+		source = strings.ToLower(u.Hostname())
+	}
+
+	root, err := rootElement(body)
+	if err != nil {
+		return nil, err
+	}
+	switch root {
+	case "rss":
+		return parseRSS(body, source, fetched)
+	case "feed":
+		return parseAtom(body, source, fetched)
+	default:
+		return nil, fmt.Errorf("неизвестный формат ленты: корневой элемент <%s>", root)
+	}
+}
+
+// rootElement возвращает имя корневого элемента XML-документа.
+func rootElement(body []byte) (string, error) {
+	d := newDecoder(body)
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return "", err
+		}
+		if se, ok := t.(xml.StartElement); ok {
+			return se.Name.Local, nil
+		}
+	}
+}
+
+// parseRSS разбирает ленту в формате RSS 2.0.
+// fetched - время получения ленты, используется для новостей без даты публикации.
+func parseRSS(body []byte, source string, fetched time.Time) ([]storage.NewsFullDetailed, error) {
 	var f Feed
-	err = xml.Unmarshal(body, &f)
+	err := newDecoder(body).Decode(&f)
 	if err != nil {
 		return nil, err
 	}
@@ -51,14 +167,121 @@ func ReadRSS(url string) ([]storage.NewsFullDetailed, error) {
 	for _, item := range f.Chanel.Items {
 		var p storage.NewsFullDetailed
 		p.Title = item.Title
-		p.Content = item.Description
-		t, err := time.Parse(time.RFC1123, item.PubDate)
-		if err != nil {
-			t, _ = time.Parse(time.RFC1123Z, item.PubDate)
-		}
+		p.Content, p.ContentText = sanitize.Clean(item.Description, item.Link)
+		t, estimated := parseDate(item.PubDate, fetched)
 		p.PubTime = t.Unix()
+		p.PubTimeEstimated = estimated
 		p.Link = item.Link
+		p.GUID = item.GUID
+		p.Source = source
+		p.Media = itemMedia(item)
+		p.Tags = item.Categories
 		postList = append(postList, p)
 	}
 	return postList, nil
 }
+
+// parseAtom разбирает ленту в формате Atom.
+// fetched - время получения ленты, используется для новостей без даты публикации.
+func parseAtom(body []byte, source string, fetched time.Time) ([]storage.NewsFullDetailed, error) {
+	var f AtomFeed
+	err := newDecoder(body).Decode(&f)
+	if err != nil {
+		return nil, err
+	}
+
+	var postList []storage.NewsFullDetailed
+	for _, entry := range f.Entries {
+		var p storage.NewsFullDetailed
+		p.Title = entry.Title
+		content := entry.Content
+		if content == "" {
+			content = entry.Summary
+		}
+		date := entry.Published
+		if date == "" {
+			date = entry.Updated
+		}
+		t, estimated := parseDate(date, fetched)
+		p.PubTime = t.Unix()
+		p.PubTimeEstimated = estimated
+		// Ссылка на новость - link с rel="alternate" (или без rel).
+		for _, l := range entry.Links {
+			if l.Rel == "" || l.Rel == "alternate" {
+				p.Link = l.Href
+				break
+			}
+		}
+		p.Content, p.ContentText = sanitize.Clean(content, p.Link)
+		p.GUID = entry.ID
+		p.Source = source
+		var m mediaList
+		for _, l := range entry.Links {
+			if l.Rel == "enclosure" {
+				m.add(storage.Media{URL: l.Href, Type: l.Type, Size: l.Length})
+			}
+		}
+		p.Media = m.items
+		// Человекочитаемое название категории предпочтительнее её идентификатора.
+		for _, c := range entry.Categories {
+			if c.Label != "" {
+				p.Tags = append(p.Tags, c.Label)
+			} else {
+				p.Tags = append(p.Tags, c.Term)
+			}
+		}
+		postList = append(postList, p)
+	}
+	return postList, nil
+}
+
+// itemMedia собирает изображения, аудио и видео новости из <enclosure>
+// и элементов Media RSS.
+func itemMedia(item Item) []storage.Media {
+	var m mediaList
+	for _, e := range item.Enclosures {
+		m.add(storage.Media{URL: e.URL, Type: e.Type, Size: e.Length})
+	}
+	contents := item.MediaContents
+	thumbnails := item.MediaThumbnails
+	for _, g := range item.MediaGroups {
+		contents = append(contents, g.Contents...)
+		thumbnails = append(thumbnails, g.Thumbnails...)
+	}
+	for _, c := range contents {
+		m.add(storage.Media{URL: c.URL, Type: c.Type, Medium: c.Medium, Size: c.FileSize, Width: c.Width, Height: c.Height})
+		thumbnails = append(thumbnails, c.Thumbnails...)
+	}
+	for _, t := range thumbnails {
+		m.add(storage.Media{URL: t.URL, Medium: "image", Width: t.Width, Height: t.Height, Thumbnail: true})
+	}
+	return m.items
+}
+
+// Список медиафайлов новости без повторов.
+type mediaList struct {
+	items []storage.Media
+	seen  map[string]bool
+}
+
+// add добавляет медиафайл, определяя его вид по MIME-типу.
+// Файлы без адреса, повторы и файлы, не являющиеся изображением, аудио или видео, пропускаются.
+func (m *mediaList) add(media storage.Media) {
+	media.URL = strings.TrimSpace(media.URL)
+	if media.URL == "" || m.seen[media.URL] {
+		return
+	}
+	if media.Medium == "" {
+		media.Medium, _, _ = strings.Cut(media.Type, "/")
+	}
+	switch media.Medium {
+	case "image", "audio", "video":
+	default:
+		return
+	}
+	if m.seen == nil {
+		m.seen = make(map[string]bool)
+	}
+	m.seen[media.URL] = true
+	m.items = append(m.items, media)
+}
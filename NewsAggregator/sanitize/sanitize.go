@@ -0,0 +1,246 @@
+// Пакет для очистки HTML-содержимого новостей.
+package sanitize
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Разрешённые теги и их разрешённые атрибуты.
+// Прочие теги удаляются с сохранением содержимого.
+var allowedTags = map[atom.Atom][]string{
+	atom.A:          {"href", "title"},
+	atom.Img:        {"src", "alt", "width", "height"},
+	atom.P:          nil,
+	atom.Br:         nil,
+	atom.B:          nil,
+	atom.Strong:     nil,
+	atom.I:          nil,
+	atom.Em:         nil,
+	atom.U:          nil,
+	atom.S:          nil,
+	atom.Sub:        nil,
+	atom.Sup:        nil,
+	atom.Ul:         nil,
+	atom.Ol:         nil,
+	atom.Li:         nil,
+	atom.Blockquote: nil,
+	atom.Pre:        nil,
+	atom.Code:       nil,
+	atom.H1:         nil,
+	atom.H2:         nil,
+	atom.H3:         nil,
+	atom.H4:         nil,
+	atom.H5:         nil,
+	atom.H6:         nil,
+	atom.Figure:     nil,
+	atom.Figcaption: nil,
+}
+
+// Теги, удаляемые вместе с содержимым.
+var droppedTags = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Iframe:   true,
+	atom.Object:   true,
+	atom.Embed:    true,
+	atom.Noscript: true,
+	atom.Form:     true,
+	atom.Template: true,
+	atom.Svg:      true,
+	atom.Math:     true,
+	atom.Head:     true,
+	atom.Title:    true,
+}
+
+// Блочные теги: в текстовой версии на их границах начинается новая строка.
+var blockTags = map[atom.Atom]bool{
+	atom.P:          true,
+	atom.Br:         true,
+	atom.Div:        true,
+	atom.Li:         true,
+	atom.Blockquote: true,
+	atom.Pre:        true,
+	atom.H1:         true,
+	atom.H2:         true,
+	atom.H3:         true,
+	atom.H4:         true,
+	atom.H5:         true,
+	atom.H6:         true,
+	atom.Figure:     true,
+	atom.Figcaption: true,
+	atom.Tr:         true,
+	atom.Table:      true,
+}
+
+// Clean возвращает очищенную HTML-версию и текстовую версию содержимого новости.
+// В HTML-версии остаются только разрешённые теги и атрибуты, ссылки приводятся
+// к абсолютным относительно base и открываются в новом окне без передачи referrer,
+// изображения-счётчики (1x1) удаляются.
+func Clean(raw, base string) (cleanHTML, text string) {
+	baseURL, _ := url.Parse(base)
+
+	// Разметка CDATA, попавшая в описание в экранированном виде.
+	raw = strings.NewReplacer("<![CDATA[", "", "]]>", "").Replace(raw)
+
+	var h, t strings.Builder
+	var open []atom.Atom // открытые разрешённые теги
+	skip := 0            // глубина вложенности удаляемых тегов
+
+	z := html.NewTokenizer(strings.NewReader(raw))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		tok := z.Token()
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if droppedTags[tok.DataAtom] {
+				if tt == html.StartTagToken {
+					skip++
+				}
+				continue
+			}
+			if skip > 0 {
+				continue
+			}
+			if blockTags[tok.DataAtom] {
+				t.WriteString("\n")
+			}
+			attrs, ok := allowedTags[tok.DataAtom]
+			if !ok {
+				continue
+			}
+			tag, ok := cleanTag(tok, attrs, baseURL)
+			if !ok {
+				continue
+			}
+			h.WriteString(tag)
+			if tt == html.StartTagToken && !isVoid(tok.DataAtom) {
+				open = append(open, tok.DataAtom)
+			}
+		case html.EndTagToken:
+			if droppedTags[tok.DataAtom] {
+				if skip > 0 {
+					skip--
+				}
+				continue
+			}
+			if skip > 0 {
+				continue
+			}
+			if blockTags[tok.DataAtom] {
+				t.WriteString("\n")
+			}
+			// Закрываем тег вместе со всеми незакрытыми вложенными тегами.
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] == tok.DataAtom {
+					for j := len(open) - 1; j >= i; j-- {
+						h.WriteString("</" + open[j].String() + ">")
+					}
+					open = open[:i]
+					break
+				}
+			}
+		case html.TextToken:
+			if skip > 0 {
+				continue
+			}
+			h.WriteString(html.EscapeString(tok.Data))
+			t.WriteString(tok.Data)
+		}
+	}
+	for i := len(open) - 1; i >= 0; i-- {
+		h.WriteString("</" + open[i].String() + ">")
+	}
+	return strings.TrimSpace(h.String()), collapseSpaces(t.String())
+}
+
+// cleanTag возвращает тег только с разрешёнными атрибутами.
+// false означает, что тег следует удалить.
+func cleanTag(tok html.Token, allowed []string, base *url.URL) (string, bool) {
+	var b strings.Builder
+	b.WriteString("<" + tok.Data)
+	var width, height string
+	hasSrc := false
+	for _, a := range tok.Attr {
+		if a.Namespace != "" || !contains(allowed, a.Key) {
+			continue
+		}
+		val := a.Val
+		switch a.Key {
+		case "href", "src":
+			link, ok := resolveLink(val, base)
+			if !ok {
+				continue
+			}
+			val = link
+			hasSrc = hasSrc || a.Key == "src"
+		case "width":
+			width = strings.TrimSpace(val)
+		case "height":
+			height = strings.TrimSpace(val)
+		}
+		b.WriteString(" " + a.Key + `="` + html.EscapeString(val) + `"`)
+	}
+	switch tok.DataAtom {
+	case atom.Img:
+		// Изображение без адреса или счётчик посещений.
+		if !hasSrc || width == "0" || width == "1" || height == "0" || height == "1" {
+			return "", false
+		}
+	case atom.A:
+		b.WriteString(` rel="nofollow noopener noreferrer" target="_blank"`)
+	}
+	if tok.Type == html.SelfClosingTagToken || isVoid(tok.DataAtom) {
+		b.WriteString(" />")
+	} else {
+		b.WriteString(">")
+	}
+	return b.String(), true
+}
+
+// resolveLink приводит ссылку к абсолютной относительно base.
+// Допускаются только ссылки http и https.
+func resolveLink(link string, base *url.URL) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return "", false
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", false
+	}
+	return u.String(), true
+}
+
+// collapseSpaces убирает лишние пробельные символы и пустые строки.
+func collapseSpaces(s string) string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func isVoid(a atom.Atom) bool {
+	return a == atom.Br || a == atom.Img
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+package sanitize
+
+import "testing"
+
+// Атрибуты, добавляемые к каждой ссылке.
+const linkAttrs = ` rel="nofollow noopener noreferrer" target="_blank"`
+
+func TestClean(t *testing.T) {
+	const base = "https://news.example.com/feed/rss"
+	tests := []struct {
+		name     string
+		raw      string
+		wantHTML string
+		wantText string
+	}{
+		// удаляемые теги и атрибуты
+		{
+			name:     "script удаляется с содержимым",
+			raw:      `<p>Текст<script>alert("x")</script> новости</p>`,
+			wantHTML: `<p>Текст новости</p>`,
+			wantText: "Текст новости",
+		},
+		{
+			name:     "style удаляется с содержимым",
+			raw:      `<style>p { color: red }</style><p>Текст</p>`,
+			wantHTML: `<p>Текст</p>`,
+			wantText: "Текст",
+		},
+		{
+			name:     "обработчики on* удаляются",
+			raw:      `<p onclick="alert(1)">Текст <img src="https://img.example.com/a.png" onerror="alert(1)" alt="фото"></p>`,
+			wantHTML: `<p>Текст <img src="https://img.example.com/a.png" alt="фото" /></p>`,
+			wantText: "Текст",
+		},
+		{
+			name:     "неразрешённые атрибуты удаляются",
+			raw:      `<b style="color:red" class="x" id="y">жирный</b>`,
+			wantHTML: `<b>жирный</b>`,
+			wantText: "жирный",
+		},
+		{
+			name:     "значение атрибута экранируется",
+			raw:      `<a href="https://example.com/" title='"><script>alert(1)</script>'>ссылка</a>`,
+			wantHTML: `<a href="https://example.com/" title="&#34;&gt;&lt;script&gt;alert(1)&lt;/script&gt;"` + linkAttrs + `>ссылка</a>`,
+			wantText: "ссылка",
+		},
+		// ссылки
+		{
+			name:     "относительная ссылка приводится к абсолютной",
+			raw:      `<a href="/news/1?id=2">ссылка</a>`,
+			wantHTML: `<a href="https://news.example.com/news/1?id=2"` + linkAttrs + `>ссылка</a>`,
+			wantText: "ссылка",
+		},
+		{
+			name:     "ссылка javascript: удаляется",
+			raw:      `<a href="javascript:alert(1)">ссылка</a>`,
+			wantHTML: `<a` + linkAttrs + `>ссылка</a>`,
+			wantText: "ссылка",
+		},
+		{
+			name:     "ссылка javascript: в другом регистре и с пробелами",
+			raw:      `<a href="  JaVaScRiPt:alert(1)">ссылка</a>`,
+			wantHTML: `<a` + linkAttrs + `>ссылка</a>`,
+			wantText: "ссылка",
+		},
+		{
+			name:     "ссылка data: удаляется",
+			raw:      `<a href="data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==">ссылка</a>`,
+			wantHTML: `<a` + linkAttrs + `>ссылка</a>`,
+			wantText: "ссылка",
+		},
+		{
+			name:     "изображение data: удаляется",
+			raw:      `<p>Текст<img src="data:image/png;base64,iVBORw0KGgo="></p>`,
+			wantHTML: `<p>Текст</p>`,
+			wantText: "Текст",
+		},
+		// изображения-счётчики
+		{
+			name:     "изображение 1x1 удаляется",
+			raw:      `<p>Текст<img src="https://stat.example.com/pixel.gif" width="1" height="1"></p>`,
+			wantHTML: `<p>Текст</p>`,
+			wantText: "Текст",
+		},
+		{
+			name:     "изображение нулевой ширины удаляется",
+			raw:      `<p>Текст<img src="https://stat.example.com/pixel.gif" width="0"></p>`,
+			wantHTML: `<p>Текст</p>`,
+			wantText: "Текст",
+		},
+		{
+			name:     "изображение без адреса удаляется",
+			raw:      `<p>Текст<img alt="фото"></p>`,
+			wantHTML: `<p>Текст</p>`,
+			wantText: "Текст",
+		},
+		{
+			name:     "обычное изображение сохраняется",
+			raw:      `<img src="/img/a.jpg" width="640" height="480">`,
+			wantHTML: `<img src="https://news.example.com/img/a.jpg" width="640" height="480" />`,
+			wantText: "",
+		},
+		// CDATA
+		{
+			name:     "экранированная разметка CDATA",
+			raw:      `<![CDATA[<p>Текст <b>новости</b></p>]]>`,
+			wantHTML: `<p>Текст <b>новости</b></p>`,
+			wantText: "Текст новости",
+		},
+		// вложенные теги
+		{
+			name:     "неразрешённые теги удаляются с сохранением содержимого",
+			raw:      `<div><span>Текст <font color="red"><b>новости</b></font></span></div>`,
+			wantHTML: `Текст <b>новости</b>`,
+			wantText: "Текст новости",
+		},
+		{
+			name:     "вложенные удаляемые теги",
+			raw:      `<form><div><b>поле</b><svg><g>рисунок</g></svg></div></form><p>Текст</p>`,
+			wantHTML: `<p>Текст</p>`,
+			wantText: "Текст",
+		},
+		{
+			name:     "незакрытые теги закрываются",
+			raw:      `<p><b><i>Текст`,
+			wantHTML: `<p><b><i>Текст</i></b></p>`,
+			wantText: "Текст",
+		},
+		{
+			name:     "закрытие внешнего тега закрывает вложенные",
+			raw:      `<p><b>Текст</p>после`,
+			wantHTML: `<p><b>Текст</b></p>после`,
+			wantText: "Текст\nпосле",
+		},
+		// текстовая версия
+		{
+			name:     "абзацы и переносы строк",
+			raw:      `<p>Первый   абзац</p><p>Второй<br>строка</p><ul><li>один</li><li>два</li></ul>`,
+			wantHTML: `<p>Первый   абзац</p><p>Второй<br />строка</p><ul><li>один</li><li>два</li></ul>`,
+			wantText: "Первый абзац\nВторой\nстрока\nодин\nдва",
+		},
+		{
+			name:     "символы HTML в тексте",
+			raw:      `a &lt; b &amp;&amp; c &gt; d`,
+			wantHTML: `a &lt; b &amp;&amp; c &gt; d`,
+			wantText: "a < b && c > d",
+		},
+		{
+			name:     "пустое содержимое",
+			raw:      "",
+			wantHTML: "",
+			wantText: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotHTML, gotText := Clean(tt.raw, base)
+			if gotHTML != tt.wantHTML {
+				t.Errorf("Clean(%q) html = %q, want %q", tt.raw, gotHTML, tt.wantHTML)
+			}
+			if gotText != tt.wantText {
+				t.Errorf("Clean(%q) text = %q, want %q", tt.raw, gotText, tt.wantText)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+)
+
+// ErrBadCursor возвращается, если курсор пагинации не удалось разобрать
+// или он не соответствует запрошенной сортировке и фильтрам.
+var ErrBadCursor = errors.New("некорректный курсор пагинации")
+
+// Позиция в списке новостей для постраничного вывода по ключу (keyset).
+// Клиенту передаётся в закодированном виде и для него непрозрачна.
+type cursor struct {
+	Sort    string `json:"s"`           // сортировка, для которой выдан курсор
+	PubTime int64  `json:"t"`           // время публикации граничной новости
+	ID      int    `json:"i"`           // id граничной новости
+	Prev    bool   `json:"p,omitempty"` // направление: к предыдущей странице
+	Filter  string `json:"f"`           // хэш фильтров и сортировки, для которых выдан курсор
+}
+
+// filterHash возвращает хэш параметров фильтрации и сортировки списка.
+// Курсор, выданный для одного набора параметров, не принимается для другого.
+// Тег и источник сравниваются после нормализации, как при поиске в БД.
+func filterHash(f NewsFilter) string {
+	h := sha256.New()
+	for _, v := range []string{
+		f.Sort, f.Search, normalizeTag(f.Tag), normalizeSource(f.Source),
+		strconv.FormatInt(f.From, 10), strconv.FormatInt(f.To, 10),
+	} {
+		h.Write([]byte(strconv.Itoa(len(v))))
+		h.Write([]byte{':'})
+		h.Write([]byte(v))
+	}
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil)[:12])
+}
+
+// encode кодирует курсор в строку, безопасную для передачи в url.
+func (c cursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor разбирает курсор, полученный от клиента.
+func decodeCursor(s string, f NewsFilter) (cursor, error) {
+	var c cursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrBadCursor
+	}
+	if err = json.Unmarshal(b, &c); err != nil || c.Sort != f.Sort || c.Filter != filterHash(f) || c.ID <= 0 {
+		return cursor{}, ErrBadCursor
+	}
+	return c, nil
+}
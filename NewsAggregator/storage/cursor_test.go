@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_decodeCursor(t *testing.T) {
+	// Курсор выдаётся для фильтра после нормализации тега и источника (как в NewsList).
+	issued := NewsFilter{Sort: SortDesc, Search: "выборы", Tag: normalizeTag(" World  News "), Source: normalizeSource("Lenta.ru"), From: 1, To: 2}
+	s := cursor{Sort: SortDesc, PubTime: 100, ID: 7, Filter: filterHash(issued)}.encode()
+
+	tests := []struct {
+		name    string
+		f       NewsFilter
+		wantErr bool
+	}{
+		{name: "тот же фильтр", f: issued},
+		{name: "тег и источник в другом регистре и с пробелами", f: NewsFilter{Sort: SortDesc, Search: "выборы", Tag: " World  News ", Source: "Lenta.ru", From: 1, To: 2}},
+		{name: "тег в верхнем регистре", f: NewsFilter{Sort: SortDesc, Search: "выборы", Tag: "WORLD NEWS", Source: "LENTA.RU", From: 1, To: 2}},
+		{name: "другой тег", f: NewsFilter{Sort: SortDesc, Search: "выборы", Tag: "sport", Source: "lenta.ru", From: 1, To: 2}, wantErr: true},
+		{name: "другой поиск", f: NewsFilter{Sort: SortDesc, Search: "спорт", Tag: "world news", Source: "lenta.ru", From: 1, To: 2}, wantErr: true},
+		{name: "другой период", f: NewsFilter{Sort: SortDesc, Search: "выборы", Tag: "world news", Source: "lenta.ru", From: 1, To: 3}, wantErr: true},
+		{name: "другая сортировка", f: NewsFilter{Sort: SortAsc, Search: "выборы", Tag: "world news", Source: "lenta.ru", From: 1, To: 2}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := decodeCursor(s, tt.f)
+			if tt.wantErr {
+				if !errors.Is(err, ErrBadCursor) {
+					t.Fatalf("decodeCursor() err = %v, want %v", err, ErrBadCursor)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeCursor() err = %v", err)
+			}
+			if c.PubTime != 100 || c.ID != 7 {
+				t.Errorf("decodeCursor() = %+v, want PubTime 100, ID 7", c)
+			}
+		})
+	}
+}
+
+func Test_decodeCursor_malformed(t *testing.T) {
+	f := NewsFilter{Sort: SortDesc}
+	for _, s := range []string{"", "не base64", cursor{Sort: SortDesc, ID: 0, Filter: filterHash(f)}.encode()} {
+		if _, err := decodeCursor(s, f); !errors.Is(err, ErrBadCursor) {
+			t.Errorf("decodeCursor(%q) err = %v, want %v", s, err, ErrBadCursor)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strings"
+)
+
+// Параметры url, не влияющие на содержимое страницы (метки рекламных кампаний
+// и идентификаторы переходов). Кроме перечисленных удаляются все utm_*.
+var trackingParams = map[string]bool{
+	"fbclid": true,
+	"gclid":  true,
+	"yclid":  true,
+	"ysclid": true,
+}
+
+// normalizeLink приводит ссылку на новость к каноническому виду для поиска дубликатов:
+// схема https, хост в нижнем регистре и без www, без фрагмента, меток отслеживания
+// и завершающего слэша, параметры запроса отсортированы.
+func normalizeLink(link string) string {
+	link = strings.TrimSpace(link)
+	u, err := url.Parse(link)
+	if err != nil || u.Host == "" {
+		return link
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme == "http" {
+		u.Scheme = "https"
+	}
+	u.Host = strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	q := u.Query()
+	for name := range q {
+		if strings.HasPrefix(strings.ToLower(name), "utm_") || trackingParams[strings.ToLower(name)] {
+			q.Del(name)
+		}
+	}
+	u.RawQuery = q.Encode()
+
+	if len(u.Path) > 1 {
+		u.Path = strings.TrimSuffix(u.Path, "/")
+		u.RawPath = ""
+	}
+	return u.String()
+}
+
+// fingerprint возвращает отпечаток новости по заголовку и содержанию
+// без учёта регистра и пробельных символов.
+func fingerprint(title, content string) string {
+	text := strings.ToLower(strings.Join(strings.Fields(title+" "+content), " "))
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// Максимальная длина тега в символах.
+const maxTagLen = 64
+
+// normalizeTag приводит тег к каноническому виду: нижний регистр,
+// без лишних пробелов, не длиннее maxTagLen символов.
+func normalizeTag(tag string) string {
+	tag = strings.ToLower(strings.Join(strings.Fields(tag), " "))
+	if r := []rune(tag); len(r) > maxTagLen {
+		tag = strings.TrimSpace(string(r[:maxTagLen]))
+	}
+	return tag
+}
+
+// normalizeSource приводит источник (хост ленты) из фильтра к виду, в котором он хранится в БД.
+func normalizeSource(source string) string {
+	return strings.ToLower(strings.TrimSpace(source))
+}
+
+// normalizeTags нормализует теги новости, исключая пустые и повторяющиеся.
+func normalizeTags(tags []string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = normalizeTag(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		out = append(out, tag)
+	}
+	return out
+}
@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"APIGateway/pkg/migrate"
+	"embed"
+)
+
+// Миграции схемы БД: migrations/<версия>_<название>.up.sql и парный .down.sql.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Ключ блокировки pg_advisory_lock, исключающей одновременное выполнение миграций
+// несколькими экземплярами сервиса.
+const migrationLockKey = 7102301
+
+// migrator возвращает исполнитель миграций схемы БД новостей.
+func (s *Storage) migrator() *migrate.Migrator {
+	return migrate.New(s.db, migrationFiles, migrationLockKey)
+}
+
+// MigrateUp применяет все ещё не применённые миграции. Возвращает применённые миграции.
+func (s *Storage) MigrateUp() ([]migrate.Migration, error) {
+	return s.migrator().Up()
+}
+
+// MigrateDown отменяет steps последних применённых миграций. Возвращает отменённые миграции.
+func (s *Storage) MigrateDown(steps int) ([]migrate.Migration, error) {
+	return s.migrator().Down(steps)
+}
+
+// MigrationStatus возвращает состояние всех миграций: известных сервису и применённых в БД.
+func (s *Storage) MigrationStatus() ([]migrate.Status, error) {
+	return s.migrator().Status()
+}
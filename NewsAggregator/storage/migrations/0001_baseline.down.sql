@@ -0,0 +1,8 @@
+-- Отмена базовой схемы: удаление всех таблиц агрегатора новостей вместе с данными.
+
+DROP TABLE IF EXISTS news_archive;
+DROP TABLE IF EXISTS news_tags;
+DROP TABLE IF EXISTS tags;
+DROP TABLE IF EXISTS news_media;
+DROP TABLE IF EXISTS news_revisions;
+DROP TABLE IF EXISTS news;
@@ -0,0 +1,100 @@
+-- Базовая схема БД агрегатора новостей.
+
+-- Таблица news, созданная ранее первоначальной schema.sql, сохраняется под именем news_legacy
+-- вместе с первичным ключом, ограничением UNIQUE (link) и последовательностью id.
+-- Её новости переносятся в новую схему миграцией 0002_legacy_news.
+ALTER TABLE IF EXISTS news RENAME TO news_legacy;
+ALTER INDEX IF EXISTS news_pkey RENAME TO news_legacy_pkey;
+ALTER INDEX IF EXISTS news_link_key RENAME TO news_legacy_link_key;
+ALTER SEQUENCE IF EXISTS news_id_seq RENAME TO news_legacy_id_seq;
+
+-- новости
+CREATE TABLE news (
+    id SERIAL PRIMARY KEY,
+    title TEXT NOT NULL,
+    content TEXT NOT NULL, -- очищенный HTML (разрешённые теги и атрибуты)
+    content_text TEXT NOT NULL DEFAULT '', -- содержание без разметки
+    pub_time BIGINT DEFAULT 0, -- время публикации (Unix)
+    pub_time_estimated BOOLEAN NOT NULL DEFAULT false, -- дата не распознана, pub_time - время получения ленты
+    link TEXT NOT NULL,
+    guid TEXT NOT NULL DEFAULT '', -- идентификатор новости в ленте (RSS guid / Atom id)
+    source TEXT NOT NULL DEFAULT '', -- источник новости (хост RSS-ленты)
+    link_norm TEXT NOT NULL, -- нормализованная ссылка: без utm_* и прочих меток, фрагмента и т.п.
+    fingerprint TEXT NOT NULL, -- sha256 заголовка и содержания
+    updated_time BIGINT DEFAULT 0, -- время последнего обновления новости в ленте
+    -- полнотекстовый индекс: заголовок (вес A) и содержание (вес B) в русской и английской конфигурациях
+    search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('russian', title), 'A') ||
+        setweight(to_tsvector('english', title), 'A') ||
+        setweight(to_tsvector('russian', content_text), 'B') ||
+        setweight(to_tsvector('english', content_text), 'B')
+    ) STORED
+);
+
+-- выборка списка новостей по времени публикации (в т.ч. с ограничением from/to)
+CREATE INDEX news_pub_time_idx ON news (pub_time DESC, id DESC);
+
+-- выборка новостей источника (фильтр source, ограничение числа новостей источника при очистке)
+CREATE INDEX news_source_pub_time_idx ON news (source, pub_time DESC);
+
+-- полнотекстовый поиск по заголовку и содержанию
+CREATE INDEX news_search_idx ON news USING GIN (search_vector);
+
+-- Дубликаты новостей исключаются по любому из уникальных индексов (INSERT ... ON CONFLICT DO NOTHING):
+-- guid уникален в пределах источника, нормализованная ссылка и отпечаток - глобально.
+-- Пустые guid и ссылки не участвуют в проверке дубликатов.
+CREATE UNIQUE INDEX news_source_guid_key ON news (source, guid) WHERE guid <> '';
+CREATE UNIQUE INDEX news_link_norm_key ON news (link_norm) WHERE link_norm <> '';
+CREATE UNIQUE INDEX news_fingerprint_key ON news (fingerprint);
+
+-- прежние редакции новостей, изменённых в ленте после добавления
+CREATE TABLE news_revisions (
+    id SERIAL PRIMARY KEY,
+    news_id INTEGER NOT NULL REFERENCES news (id) ON DELETE CASCADE,
+    title TEXT NOT NULL,
+    content TEXT NOT NULL, -- очищенный HTML (разрешённые теги и атрибуты)
+    content_text TEXT NOT NULL DEFAULT '', -- содержание без разметки
+    revised_time BIGINT NOT NULL -- время замены редакции новой
+);
+
+CREATE INDEX news_revisions_news_id_idx ON news_revisions (news_id, id);
+
+-- изображения, аудио и видео новостей (RSS enclosure, Media RSS)
+CREATE TABLE news_media (
+    id SERIAL PRIMARY KEY,
+    news_id INTEGER NOT NULL REFERENCES news (id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    mime_type TEXT NOT NULL DEFAULT '',
+    medium TEXT NOT NULL, -- image, audio, video
+    size BIGINT NOT NULL DEFAULT 0, -- размер в байтах
+    width INTEGER NOT NULL DEFAULT 0,
+    height INTEGER NOT NULL DEFAULT 0,
+    thumbnail BOOLEAN NOT NULL DEFAULT false, -- миниатюра новости
+    UNIQUE (news_id, url)
+);
+
+-- теги (категории) новостей в нормализованном виде: нижний регистр, без лишних пробелов
+CREATE TABLE tags (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE news_tags (
+    news_id INTEGER NOT NULL REFERENCES news (id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+    PRIMARY KEY (news_id, tag_id)
+);
+
+CREATE INDEX news_tags_tag_id_idx ON news_tags (tag_id);
+
+-- архив новостей, удалённых при очистке (retention): новость с медиафайлами, тегами и редакциями в JSON
+CREATE TABLE news_archive (
+    id SERIAL PRIMARY KEY,
+    news_id INTEGER NOT NULL, -- id новости в таблице news на момент переноса
+    source TEXT NOT NULL DEFAULT '',
+    pub_time BIGINT NOT NULL DEFAULT 0,
+    archived_time BIGINT NOT NULL, -- время переноса в архив (Unix)
+    data JSONB NOT NULL
+);
+
+CREATE INDEX news_archive_pub_time_idx ON news_archive (pub_time);
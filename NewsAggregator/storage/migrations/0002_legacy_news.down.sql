@@ -0,0 +1 @@
+-- Перенесённые новости остаются в таблице news: прежняя таблица news_legacy не восстанавливается.
@@ -0,0 +1,35 @@
+-- Перенос новостей из таблицы news_legacy (БД, созданная первоначальной schema.sql,
+-- см. 0001_baseline) в таблицу news с сохранением id. Для новых БД миграция ничего не меняет.
+-- Недостающие столбцы заполняются по имеющимся данным:
+-- content_text - содержание без HTML-тегов, link_norm - исходная ссылка (уникальна в news_legacy),
+-- fingerprint - sha256 заголовка и content_text без учёта регистра и пробелов, как в AddNews;
+-- совпадающие отпечатки разных новостей дополняются id, чтобы не нарушить уникальный индекс.
+-- Новости, дата которых не была распознана (pub_time до 1971 года), помечаются флагом pub_time_estimated.
+DO $$
+BEGIN
+    IF to_regclass('news_legacy') IS NULL THEN
+        RETURN;
+    END IF;
+
+    INSERT INTO news (id, title, content, content_text, pub_time, pub_time_estimated, link, link_norm, fingerprint)
+    SELECT
+        id, title, content, content_text, pub_time, pub_time < 31536000, link, link,
+        CASE WHEN row_number() OVER (PARTITION BY h ORDER BY id) = 1 THEN h ELSE h || '-' || id END
+    FROM (
+        SELECT
+            *,
+            encode(sha256(convert_to(lower(regexp_replace(btrim(title || ' ' || content_text), '\s+', ' ', 'g')), 'UTF8')), 'hex') AS h
+        FROM (
+            SELECT
+                id, title, content, COALESCE(pub_time, 0) AS pub_time, link,
+                btrim(regexp_replace(regexp_replace(content, '<[^>]*>', ' ', 'g'), '\s+', ' ', 'g')) AS content_text
+            FROM news_legacy
+        ) AS l
+    ) AS t;
+
+    -- новые новости получают id после перенесённых
+    PERFORM setval(pg_get_serial_sequence('news', 'id'), max(id)) FROM news HAVING max(id) IS NOT NULL;
+
+    DROP TABLE news_legacy;
+END
+$$;
@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"context"
+	"log"
+)
+
+// Новость для архива в формате JSON: строка news без поискового вектора,
+// медиафайлы, теги и прежние редакции.
+const archiveNewsJSON = `
+			(to_jsonb(news) - 'search_vector') || jsonb_build_object(
+				'media', (
+					SELECT COALESCE(jsonb_agg(to_jsonb(m) - 'news_id' ORDER BY m.id), '[]')
+					FROM news_media m WHERE m.news_id = news.id
+				),
+				'tags', (
+					SELECT COALESCE(jsonb_agg(t.name ORDER BY t.name), '[]')
+					FROM news_tags nt JOIN tags t ON t.id = nt.tag_id WHERE nt.news_id = news.id
+				),
+				'revisions', (
+					SELECT COALESCE(jsonb_agg(to_jsonb(r) - 'news_id' ORDER BY r.id), '[]')
+					FROM news_revisions r WHERE r.news_id = news.id
+				)
+			)`
+
+// RetentionCandidates возвращает до limit id новостей, подлежащих удалению, от старых к новым:
+// опубликованные раньше before или не входящие в maxPerSource последних новостей своего источника.
+// Нулевые before и maxPerSource означают отсутствие соответствующего ограничения.
+// Новости из exclude не возвращаются.
+func (s *Storage) RetentionCandidates(before int64, maxPerSource, limit int, exclude []int) ([]int, error) {
+	// Пустой (nil) срез передаётся в запрос как NULL, а id <> ALL(NULL) не выполняется ни для одной строки.
+	if exclude == nil {
+		exclude = []int{}
+	}
+	rows, err := s.db.Query(context.Background(), `
+		SELECT
+			id
+		FROM (
+			SELECT
+				id,
+				pub_time,
+				row_number() OVER (PARTITION BY source ORDER BY pub_time DESC, id DESC) AS n
+			FROM news
+		) AS ranked
+		WHERE (($1 > 0 AND pub_time < $1) OR ($2 > 0 AND n > $2))
+			AND id <> ALL($4)
+		ORDER BY pub_time, id
+		LIMIT $3;
+	`, before, maxPerSource, limit, exclude,
+	)
+	if err != nil {
+		log.Printf("ошибка запроса в БД (отбор новостей для удаления): %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		err = rows.Scan(&id)
+		if err != nil {
+			log.Printf("ошибка чтения полученных данных из БД (отбор новостей для удаления): %v", err)
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// ArchiveToTable переносит новости в таблицу news_archive и удаляет их из news
+// (вместе с медиафайлами, тегами и редакциями). Возвращает число перенесённых новостей.
+func (s *Storage) ArchiveToTable(news_ids []int, archivedTime int64) (int64, error) {
+	tag, err := s.db.Exec(context.Background(), `
+		WITH archived AS (
+			INSERT INTO news_archive (news_id, source, pub_time, archived_time, data)
+			SELECT
+				id,
+				source,
+				pub_time,
+				$2,
+				`+archiveNewsJSON+`
+			FROM news
+			WHERE id = ANY($1)
+			RETURNING news_id
+		)
+		DELETE FROM news
+		WHERE id IN (SELECT news_id FROM archived);
+	`, news_ids, archivedTime,
+	)
+	if err != nil {
+		log.Printf("ошибка запроса в БД (перенос новостей в архив): %v", err)
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ArchiveData возвращает новости в формате JSON для записи в файл архива.
+func (s *Storage) ArchiveData(news_ids []int) ([][]byte, error) {
+	rows, err := s.db.Query(context.Background(), `
+		SELECT
+			(`+archiveNewsJSON+`)::text
+		FROM news
+		WHERE id = ANY($1)
+		ORDER BY pub_time, id;
+	`, news_ids,
+	)
+	if err != nil {
+		log.Printf("ошибка запроса в БД (новости для архива): %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var data [][]byte
+	for rows.Next() {
+		var b []byte
+		err = rows.Scan(&b)
+		if err != nil {
+			log.Printf("ошибка чтения полученных данных из БД (новости для архива): %v", err)
+			return nil, err
+		}
+		data = append(data, b)
+	}
+	return data, rows.Err()
+}
+
+// DeleteNews удаляет новости вместе с медиафайлами, тегами и редакциями.
+// Возвращает число удалённых новостей.
+func (s *Storage) DeleteNews(news_ids []int) (int64, error) {
+	tag, err := s.db.Exec(context.Background(), `
+		DELETE FROM news
+		WHERE id = ANY($1);
+	`, news_ids,
+	)
+	if err != nil {
+		log.Printf("ошибка запроса в БД (удаление новостей): %v", err)
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -3,10 +3,14 @@ package storage
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"time"
 
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
@@ -16,17 +20,28 @@ type Storage struct {
 }
 
 type Pagination struct {
-	Page       int `json:"Page"`       // текущий номер страницы
-	NewsOnPage int `json:"NewsOnPage"` // количество заголовков новостей на странице
-	TotalPages int `json:"TotalPages"` // общее число страниц
-	TotalNews  int `json:"TotalNews"`  // общее число новостей в БД
+	Page        int    `json:"Page,omitempty"`        // текущий номер страницы (нет в режиме курсора)
+	NewsOnPage  int    `json:"NewsOnPage"`            // количество заголовков новостей на странице
+	TotalPages  *int   `json:"TotalPages,omitempty"`  // общее число страниц (если известно общее число новостей)
+	TotalNews   *int   `json:"TotalNews,omitempty"`   // общее число новостей в БД (если запрошено)
+	TotalApprox bool   `json:"TotalApprox,omitempty"` // TotalNews - оценка, а не точное значение
+	NextCursor  string `json:"NextCursor,omitempty"`  // курсор следующей страницы
+	PrevCursor  string `json:"PrevCursor,omitempty"`  // курсор предыдущей страницы
 }
 
 // Коротко описывает новость для списка новостей.
 type NewsShortDetailed struct {
-	ID      int    `json:"ID"`      // уникальный идентификатор новости
-	Title   string `json:"Title"`   // заголовок новости
-	PubTime int64  `json:"PubTime"` // время новости
+	ID             int     `json:"ID"`                       // уникальный идентификатор новости
+	Title          string  `json:"Title"`                    // заголовок новости
+	PubTime        int64   `json:"PubTime"`                  // время новости
+	Rank           float32 `json:"Rank,omitempty"`           // ранг новости в результатах поиска
+	TitleHighlight string  `json:"TitleHighlight,omitempty"` // HTML: экранированный заголовок с выделенными (<b>) найденными словами
+	Snippet        string  `json:"Snippet,omitempty"`        // HTML: экранированный фрагмент содержания с выделенными найденными словами
+	Thumbnail      string  `json:"Thumbnail,omitempty"`      // миниатюра новости
+	Link           string  `json:"Link"`                     // ссылка на источник
+	Source         string  `json:"Source"`                   // источник новости (хост RSS-ленты)
+	UpdatedTime    int64   `json:"UpdatedTime,omitempty"`    // время последнего обновления новости в ленте
+	Summary        string  `json:"Summary,omitempty"`        // начало содержания без разметки (если запрошено)
 }
 
 // Структура для ответа на запрос списка новостей. С пагинацией.
@@ -44,8 +59,109 @@ type NewsFullDetailed struct {
 	PubTime int64  // время публикации новости
 	Link    string // ссылка на источник
 	Error   int    // данное поле служит для информирования клиента об ошибке
+
+	UpdatedTime int64 // время последнего обновления новости в ленте (0 - не обновлялась)
+
+	PubTimeEstimated bool // дата публикации не распознана, PubTime - время получения ленты
+
+	ContentText string `json:"-"` // содержание новости без разметки; Content - очищенный HTML
+
+	Media []Media // изображения, аудио и видео новости
+
+	Tags []string // теги (категории) новости
+
+	GUID   string `json:"-"` // идентификатор новости в ленте (RSS guid / Atom id)
+	Source string `json:"-"` // источник новости (хост RSS-ленты)
+}
+
+// Результат добавления новости в БД.
+type AddResult struct {
+	Link     string // ссылка на новость
+	ID       int    // id добавленной новости
+	Inserted bool   // новость добавлена; false - дубликат или ошибка
+	Updated  bool   // ранее добавленная новость обновлена
+	Err      error  // ошибка добавления новости
+}
+
+// Медиафайл новости (вложение RSS, Media RSS).
+type Media struct {
+	URL       string `json:"URL"`                 // адрес файла
+	Type      string `json:"Type,omitempty"`      // MIME-тип
+	Medium    string `json:"Medium"`              // вид: image, audio, video
+	Size      int64  `json:"Size,omitempty"`      // размер в байтах
+	Width     int    `json:"Width,omitempty"`     // ширина изображения или видео
+	Height    int    `json:"Height,omitempty"`    // высота изображения или видео
+	Thumbnail bool   `json:"Thumbnail,omitempty"` // миниатюра новости
+}
+
+// Столбцы медиафайлов новости для передачи в запрос массивами.
+type mediaColumns struct {
+	url, mimeType, medium []string
+	size                  []int64
+	width, height         []int32
+	thumbnail             []bool
+}
+
+func (c *mediaColumns) add(m Media) {
+	c.url = append(c.url, m.URL)
+	c.mimeType = append(c.mimeType, m.Type)
+	c.medium = append(c.medium, m.Medium)
+	c.size = append(c.size, m.Size)
+	c.width = append(c.width, int32(m.Width))
+	c.height = append(c.height, int32(m.Height))
+	c.thumbnail = append(c.thumbnail, m.Thumbnail)
+}
+
+// Тег и число новостей с ним.
+type TagCount struct {
+	Tag   string `json:"Tag"`
+	Count int    `json:"Count"`
+}
+
+// Прежняя редакция новости.
+type Revision struct {
+	ID          int    `json:"ID"`          // уникальный идентификатор редакции
+	NewsID      int    `json:"NewsID"`      // уникальный идентификатор новости
+	Title       string `json:"Title"`       // заголовок новости в этой редакции
+	Content     string `json:"Content"`     // содержание новости в этой редакции
+	RevisedTime int64  `json:"RevisedTime"` // время замены редакции новой
+}
+
+// ErrInvalidNews возвращается для новостей без заголовка или ссылки.
+var ErrInvalidNews = errors.New("новость без заголовка или ссылки")
+
+// Варианты сортировки списка новостей.
+const (
+	SortDesc      = "desc"      // сначала новые (по умолчанию)
+	SortAsc       = "asc"       // сначала старые
+	SortRelevance = "relevance" // по рангу полнотекстового поиска
+)
+
+// Параметры запроса списка новостей.
+type NewsFilter struct {
+	Amount int    // число новостей на странице
+	Page   int    // порядковый номер страницы
+	Search string // полнотекстовый поиск по заголовку и содержанию
+	From   int64  // нижняя граница времени публикации (Unix), 0 - без ограничения
+	To     int64  // верхняя граница времени публикации (Unix), 0 - без ограничения
+	Sort   string // порядок сортировки: desc, asc, relevance
+	Tag    string // тег новости, пустой - без ограничения
+	Source string // источник новости (хост RSS-ленты), пустой - без ограничения
+
+	Summary bool // добавить к новостям начало содержания без разметки
+
+	CursorMode bool   // постраничный вывод по курсору вместо номера страницы
+	Cursor     string // курсор, полученный в предыдущем ответе; пустой - первая страница
+	Total      string // способ подсчёта общего числа новостей: exact, approx, none
 }
 
+// Способы подсчёта общего числа новостей для списка.
+const (
+	TotalExact  = "exact"  // точный подсчёт count()
+	TotalApprox = "approx" // оценка по плану запроса
+	TotalNone   = "none"   // без подсчёта
+)
+
 var Host = os.Getenv("DB_HOST")
 var Port = os.Getenv("DB_PORT")
 var User = os.Getenv("DB_USER")
@@ -66,21 +182,56 @@ func New() (*Storage, error) {
 	return &s, nil
 }
 
-// NewsList возвращает n новостей из БД для указанной страницы.
-func (s *Storage) NewsList(amount, page int, search, uniqueReqID string) (PaginationNewsList, error) {
-	offset := amount * (page - 1)
+// NewsList возвращает n новостей из БД для указанной страницы
+// или, в режиме курсора, для позиции, заданной курсором.
+func (s *Storage) NewsList(f NewsFilter, uniqueReqID string) (PaginationNewsList, error) {
+	var c cursor
+	f.Tag = normalizeTag(f.Tag)
+	f.Source = normalizeSource(f.Source)
+	if f.CursorMode && f.Sort == SortRelevance {
+		log.Printf("request_id %s: курсор не поддерживается для сортировки %s", uniqueReqID, f.Sort)
+		return PaginationNewsList{}, ErrBadCursor
+	}
+	if f.Cursor != "" {
+		var err error
+		c, err = decodeCursor(f.Cursor, f)
+		if err != nil {
+			log.Printf("request_id %s: курсор %s: %v", uniqueReqID, f.Cursor, err)
+			return PaginationNewsList{}, err
+		}
+	}
+
+	// В режиме курсора вместо OFFSET используется сравнение с граничной новостью,
+	// а новостей запрашивается на одну больше, чтобы узнать, есть ли следующая страница.
+	where := newsListWhere
+	args := []interface{}{f.Search, f.From, f.To, f.Tag, f.Source}
+	if f.CursorMode {
+		args = append(args, f.Amount+1, 0)
+		if f.Cursor != "" {
+			where += " AND " + newsListKeyset(f.Sort, c.Prev)
+			args = append(args, c.PubTime, c.ID)
+		}
+	} else {
+		args = append(args, f.Amount, f.Amount*(f.Page-1))
+	}
+
 	rows, err := s.db.Query(context.Background(), `
 		SELECT 
 			id,
 			title,
-			pub_time
-		FROM news
-		WHERE
-		title ILIKE '%'||$3||'%'
-		ORDER BY pub_time DESC
-		LIMIT $1
-		OFFSET $2;
-	`, amount, offset, search,
+			pub_time,
+			`+newsListSearchColumns+`,
+			`+newsListThumbnail+`,
+			link,
+			source,
+			updated_time,
+			`+newsListSummary(f.Summary)+`
+		FROM `+newsListFrom+`
+		WHERE `+where+`
+		ORDER BY `+newsListOrder(f.Sort, c.Prev)+`
+		LIMIT $6
+		OFFSET $7;
+	`, args...,
 	)
 	if err != nil {
 		log.Printf("request_id %s: ошибка запроса в БД (получения списка новостей): %v", uniqueReqID, err)
@@ -95,6 +246,14 @@ func (s *Storage) NewsList(amount, page int, search, uniqueReqID string) (Pagina
 			&p.ID,
 			&p.Title,
 			&p.PubTime,
+			&p.Rank,
+			&p.TitleHighlight,
+			&p.Snippet,
+			&p.Thumbnail,
+			&p.Link,
+			&p.Source,
+			&p.UpdatedTime,
+			&p.Summary,
 		)
 		if err != nil {
 			log.Printf("request_id %s: ошибка чтения полученных данных из БД (список новостей): %v", uniqueReqID, err)
@@ -103,40 +262,214 @@ func (s *Storage) NewsList(amount, page int, search, uniqueReqID string) (Pagina
 		// добавление переменной в массив результатов
 		news = append(news, p)
 	}
+	if err = rows.Err(); err != nil {
+		log.Printf("request_id %s: ошибка чтения полученных данных из БД (список новостей): %v", uniqueReqID, err)
+		return PaginationNewsList{}, err
+	}
+
 	var pag Pagination
+	pag.NewsOnPage = f.Amount
+	if f.CursorMode {
+		more := len(news) > f.Amount
+		if more {
+			news = news[:f.Amount]
+		}
+		// Страница к началу списка выбиралась в обратном порядке.
+		if c.Prev {
+			for i, j := 0, len(news)-1; i < j; i, j = i+1, j-1 {
+				news[i], news[j] = news[j], news[i]
+			}
+		}
+		if len(news) > 0 {
+			first, last := news[0], news[len(news)-1]
+			if (c.Prev && more) || (!c.Prev && f.Cursor != "") {
+				pag.PrevCursor = cursor{Sort: f.Sort, PubTime: first.PubTime, ID: first.ID, Prev: true, Filter: filterHash(f)}.encode()
+			}
+			if (!c.Prev && more) || c.Prev {
+				pag.NextCursor = cursor{Sort: f.Sort, PubTime: last.PubTime, ID: last.ID, Filter: filterHash(f)}.encode()
+			}
+		}
+	} else {
+		pag.Page = f.Page
+	}
+
+	switch f.Total {
+	case TotalExact:
+		total, err := s.newsCount(f, uniqueReqID)
+		if err != nil {
+			return PaginationNewsList{}, err
+		}
+		pag.TotalNews = &total
+	case TotalApprox:
+		total, err := s.newsCountEstimate(f, uniqueReqID)
+		if err != nil {
+			return PaginationNewsList{}, err
+		}
+		pag.TotalNews = &total
+		pag.TotalApprox = true
+	}
+	if pag.TotalNews != nil && !f.CursorMode {
+		pages := *pag.TotalNews / f.Amount
+		if *pag.TotalNews%f.Amount != 0 {
+			pages++
+		}
+		pag.TotalPages = &pages
+	}
+
+	var pagNewsList PaginationNewsList
+	pagNewsList.NewsList = news
+	pagNewsList.PaginationInfo = pag
+	return pagNewsList, nil
+}
+
+// newsCount возвращает точное число новостей, удовлетворяющих фильтру.
+func (s *Storage) newsCount(f NewsFilter, uniqueReqID string) (int, error) {
+	var total int
 	rowsP, err := s.db.Query(context.Background(), `
 	SELECT 
 		count(id)
-	FROM 
-		news
-	WHERE
-		title ILIKE '%'||$1||'%';;
-`, search,
+	FROM `+newsListFrom+`
+	WHERE `+newsListWhere+`;
+`, f.Search, f.From, f.To, f.Tag, f.Source,
 	)
 	if err != nil {
 		log.Printf("request_id %s: ошибка запроса в БД (подсчёт общего числа новостей): %v", uniqueReqID, err)
-		return PaginationNewsList{}, err
+		return 0, err
 	}
 	for rowsP.Next() {
 		err = rowsP.Scan(
-			&pag.TotalNews,
+			&total,
 		)
 		if err != nil {
 			log.Printf("request_id %s: ошибка чтения полученных данных из БД (общее число новостей): %v", uniqueReqID, err)
-			return PaginationNewsList{}, err
+			return 0, err
 		}
 	}
-	pag.Page = page
-	pag.NewsOnPage = amount
-	if pag.TotalNews%amount != 0 {
-		pag.TotalPages = (pag.TotalNews / amount) + 1
-	} else {
-		pag.TotalPages = pag.TotalNews / amount
+	return total, rowsP.Err()
+}
+
+// newsCountEstimate возвращает оценку числа новостей, удовлетворяющих фильтру,
+// по плану запроса. В отличие от count() не требует просмотра всех строк.
+func (s *Storage) newsCountEstimate(f NewsFilter, uniqueReqID string) (int, error) {
+	var plan string
+	err := s.db.QueryRow(context.Background(), `
+	EXPLAIN (FORMAT JSON)
+	SELECT 
+		id
+	FROM `+newsListFrom+`
+	WHERE `+newsListWhere+`;
+`, f.Search, f.From, f.To, f.Tag, f.Source,
+	).Scan(&plan)
+	if err != nil {
+		log.Printf("request_id %s: ошибка запроса в БД (оценка общего числа новостей): %v", uniqueReqID, err)
+		return 0, err
 	}
-	var pagNewsList PaginationNewsList
-	pagNewsList.NewsList = news
-	pagNewsList.PaginationInfo = pag
-	return pagNewsList, rows.Err()
+	var explain []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	err = json.Unmarshal([]byte(plan), &explain)
+	if err != nil || len(explain) == 0 {
+		log.Printf("request_id %s: ошибка разбора плана запроса (оценка общего числа новостей): %v", uniqueReqID, err)
+		return 0, fmt.Errorf("план запроса не разобран: %v", err)
+	}
+	return int(explain[0].Plan.Rows), nil
+}
+
+// Источник строк для списка новостей: таблица news и поисковый запрос,
+// построенный из строки поиска $1 (синтаксис websearch: "фраза", or, -исключение)
+// для русской и английской конфигураций (ru - запрос только в русской конфигурации).
+const newsListFrom = `
+		news,
+		(SELECT ru, ru || en AS q FROM (
+			SELECT websearch_to_tsquery('russian', $1) AS ru, websearch_to_tsquery('english', $1) AS en
+		) AS t) AS search`
+
+// Условие отбора новостей для списка: $1 - строка поиска, $2 и $3 - границы времени публикации,
+// $4 - тег, $5 - источник. Пустые строка поиска, тег и источник и нулевая граница
+// означают отсутствие ограничения.
+const newsListWhere = `
+		($1 = '' OR search_vector @@ search.q)
+		AND ($2 = 0 OR pub_time >= $2)
+		AND ($3 = 0 OR pub_time <= $3)
+		AND ($4 = '' OR EXISTS (
+			SELECT 1 FROM news_tags nt JOIN tags t ON t.id = nt.tag_id
+			WHERE nt.news_id = news.id AND t.name = $4
+		))
+		AND ($5 = '' OR source = $5)`
+
+// Ранг новости и подсветка найденных слов в заголовке и фрагменте текста.
+// Вычисляются только при поиске.
+var newsListSearchColumns = `
+			CASE WHEN $1 = '' THEN 0 ELSE ts_rank_cd(search_vector, search.q) END,
+			` + newsListHighlight("title", "HighlightAll=true") + `,
+			` + newsListHighlight("content_text", "MaxFragments=2, MaxWords=25, MinWords=10")
+
+// newsListHighlight возвращает столбец с текстом column, в котором найденные слова выделены тегом <b>.
+// Текст хранится без разметки, поэтому перед выделением экранируются символы HTML:
+// результат - HTML, безопасный для вставки в страницу.
+// Слова выделяются в той же конфигурации, по которой текст совпал с запросом:
+// в русской, а если совпадения в ней нет - в английской.
+func newsListHighlight(column, options string) string {
+	escaped := fmt.Sprintf(`replace(replace(replace(replace(%s, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;')`, column)
+	return fmt.Sprintf(`CASE
+				WHEN $1 = '' THEN ''
+				WHEN to_tsvector('russian', %[1]s) @@ search.ru THEN ts_headline('russian', %[2]s, search.q, '%[3]s')
+				ELSE ts_headline('english', %[2]s, search.q, '%[3]s')
+			END`, column, escaped, options)
+}
+
+// Миниатюра новости для списка: первая миниатюра или изображение новости.
+const newsListThumbnail = `
+			COALESCE((
+				SELECT m.url FROM news_media m
+				WHERE m.news_id = news.id AND m.medium = 'image'
+				ORDER BY m.thumbnail DESC, m.id
+				LIMIT 1
+			), '')`
+
+// Длина начала содержания новости, возвращаемого в списке (в символах).
+const summaryLen = 500
+
+// newsListSummary возвращает столбец с началом содержания новости без разметки
+// или пустую строку, если оно не запрошено.
+func newsListSummary(summary bool) string {
+	if !summary {
+		return "''"
+	}
+	return fmt.Sprintf("left(content_text, %d)", summaryLen)
+}
+
+// newsListOrder возвращает выражение ORDER BY для указанного порядка сортировки.
+// id добавлен для однозначного порядка новостей с одинаковым временем публикации.
+// reverse обращает порядок: используется при выборке предыдущей страницы по курсору.
+func newsListOrder(sort string, reverse bool) string {
+	if reverse {
+		switch sort {
+		case SortAsc:
+			sort = SortDesc
+		case SortDesc:
+			sort = SortAsc
+		}
+	}
+	switch sort {
+	case SortAsc:
+		return "pub_time ASC, id ASC"
+	case SortRelevance:
+		return "ts_rank_cd(search_vector, search.q) DESC, pub_time DESC, id DESC"
+	default:
+		return "pub_time DESC, id DESC"
+	}
+}
+
+// newsListKeyset возвращает условие отбора новостей, следующих за граничной новостью
+// курсора ($8 - время публикации, $9 - id) в порядке сортировки.
+func newsListKeyset(sort string, prev bool) string {
+	if (sort == SortAsc) != prev {
+		return "(pub_time, id) > ($8, $9)"
+	}
+	return "(pub_time, id) < ($8, $9)"
 }
 
 // News возвращает полную новость из БД.
@@ -146,8 +479,11 @@ func (s *Storage) News(news_id int, uniqueReqID string) (*NewsFullDetailed, erro
 			id,
 			title,
 			content,
+			content_text,
 			pub_time,
-			link
+			pub_time_estimated,
+			link,
+			updated_time
 		FROM news
 		WHERE id=$1;
 	`, news_id,
@@ -167,34 +503,356 @@ func (s *Storage) News(news_id int, uniqueReqID string) (*NewsFullDetailed, erro
 			&p.ID,
 			&p.Title,
 			&p.Content,
+			&p.ContentText,
 			&p.PubTime,
+			&p.PubTimeEstimated,
 			&p.Link,
+			&p.UpdatedTime,
 		)
 		if err != nil {
 			log.Printf("request_id %s: ошибка чтения полученных данных из БД (новость %d): %v", uniqueReqID, news_id, err)
 			return nil, err
 		}
 	}
-	return &p, rows.Err()
+	if err = rows.Err(); err != nil || p.ID == 0 {
+		return &p, err
+	}
+	p.Media, err = s.newsMedia(news_id, uniqueReqID)
+	if err != nil {
+		return nil, err
+	}
+	p.Tags, err = s.newsTags(news_id, uniqueReqID)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// newsTags возвращает теги новости в алфавитном порядке.
+func (s *Storage) newsTags(news_id int, uniqueReqID string) ([]string, error) {
+	rows, err := s.db.Query(context.Background(), `
+		SELECT 
+			t.name
+		FROM news_tags nt
+		JOIN tags t ON t.id = nt.tag_id
+		WHERE nt.news_id=$1
+		ORDER BY t.name;
+	`, news_id,
+	)
+	if err != nil {
+		log.Printf("request_id %s: ошибка запроса в БД (теги новости %d): %v", uniqueReqID, news_id, err)
+		return nil, err
+	}
+	var tags []string
+	for rows.Next() {
+		var tag string
+		err = rows.Scan(&tag)
+		if err != nil {
+			log.Printf("request_id %s: ошибка чтения полученных данных из БД (теги новости %d): %v", uniqueReqID, news_id, err)
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// Tags возвращает не более limit самых частых тегов новостей,
+// опубликованных в промежутке [from, to] (Unix).
+func (s *Storage) Tags(from, to int64, limit int, uniqueReqID string) ([]TagCount, error) {
+	rows, err := s.db.Query(context.Background(), `
+		SELECT 
+			t.name,
+			count(*) AS cnt
+		FROM news_tags nt
+		JOIN tags t ON t.id = nt.tag_id
+		JOIN news n ON n.id = nt.news_id
+		WHERE n.pub_time >= $1 AND n.pub_time <= $2
+		GROUP BY t.name
+		ORDER BY cnt DESC, t.name
+		LIMIT $3;
+	`, from, to, limit,
+	)
+	if err != nil {
+		log.Printf("request_id %s: ошибка запроса в БД (список тегов): %v", uniqueReqID, err)
+		return nil, err
+	}
+	var tags []TagCount
+	for rows.Next() {
+		var t TagCount
+		err = rows.Scan(
+			&t.Tag,
+			&t.Count,
+		)
+		if err != nil {
+			log.Printf("request_id %s: ошибка чтения полученных данных из БД (список тегов): %v", uniqueReqID, err)
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
 }
 
-// AddNews добовляет новость в базу.
-func (s *Storage) AddNews(p []NewsFullDetailed) error {
-	for _, post := range p {
-		err := s.db.QueryRow(context.Background(), `
-		INSERT INTO news (title, content, pub_time, link)
-		VALUES ($1, $2, $3, $4) RETURNING id;
+// newsMedia возвращает медиафайлы новости: сначала миниатюры.
+func (s *Storage) newsMedia(news_id int, uniqueReqID string) ([]Media, error) {
+	rows, err := s.db.Query(context.Background(), `
+		SELECT 
+			url,
+			mime_type,
+			medium,
+			size,
+			width,
+			height,
+			thumbnail
+		FROM news_media
+		WHERE news_id=$1
+		ORDER BY thumbnail DESC, id;
+	`, news_id,
+	)
+	if err != nil {
+		log.Printf("request_id %s: ошибка запроса в БД (медиафайлы новости %d): %v", uniqueReqID, news_id, err)
+		return nil, err
+	}
+	var media []Media
+	for rows.Next() {
+		var m Media
+		err = rows.Scan(
+			&m.URL,
+			&m.Type,
+			&m.Medium,
+			&m.Size,
+			&m.Width,
+			&m.Height,
+			&m.Thumbnail,
+		)
+		if err != nil {
+			log.Printf("request_id %s: ошибка чтения полученных данных из БД (медиафайлы новости %d): %v", uniqueReqID, news_id, err)
+			return nil, err
+		}
+		media = append(media, m)
+	}
+	return media, rows.Err()
+}
+
+// AddNews добовляет новости в базу одним пакетом запросов в транзакции, пропуская дубликаты.
+// Новость считается дубликатом, если в БД уже есть новость того же источника
+// с тем же guid, новость с той же нормализованной ссылкой или с тем же отпечатком
+// заголовка и содержания. Если у новости с тем же guid или ссылкой изменились
+// заголовок или содержание, новость обновляется, а прежняя редакция сохраняется
+// в news_revisions. Результат возвращается для каждой новости в порядке p.
+// При ошибке БД транзакция откатывается и не сохраняется ни одна новость пакета:
+// ни одна новость в результате не отмечается добавленной или обновлённой.
+func (s *Storage) AddNews(p []NewsFullDetailed) ([]AddResult, error) {
+	results := make([]AddResult, len(p))
+	now := time.Now().Unix()
+	batch := &pgx.Batch{}
+	queued := make([]int, 0, len(p)) // индексы новостей, поставленных в пакет
+	for i, post := range p {
+		results[i].Link = post.Link
+		if post.Title == "" || post.Link == "" {
+			results[i].Err = ErrInvalidNews
+			continue
+		}
+		// Медиафайлы добавляются тем же запросом, если добавлена новость.
+		var media mediaColumns
+		for _, m := range post.Media {
+			media.add(m)
+		}
+		batch.Queue(`
+		WITH n AS (
+			INSERT INTO news (title, content, content_text, pub_time, link, guid, source, link_norm, fingerprint, pub_time_estimated)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $18)
+			ON CONFLICT DO NOTHING
+			RETURNING id
+		), tag AS (
+			INSERT INTO tags (name)
+			SELECT DISTINCT unnest($17::text[]) WHERE EXISTS (SELECT 1 FROM n)
+			ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+			RETURNING id
+		), news_tag AS (
+			INSERT INTO news_tags (news_id, tag_id)
+			SELECT n.id, tag.id FROM n, tag
+		), media AS (
+			INSERT INTO news_media (news_id, url, mime_type, medium, size, width, height, thumbnail)
+			SELECT n.id, m.url, m.mime_type, m.medium, m.size, m.width, m.height, m.thumbnail
+			FROM n, unnest($10::text[], $11::text[], $12::text[], $13::bigint[], $14::integer[], $15::integer[], $16::boolean[])
+				AS m(url, mime_type, medium, size, width, height, thumbnail)
+			ON CONFLICT DO NOTHING
+		)
+		SELECT id FROM n;
 		`,
 			post.Title,
 			post.Content,
+			post.ContentText,
 			post.PubTime,
 			post.Link,
-		).Scan(&post.ID)
+			post.GUID,
+			post.Source,
+			normalizeLink(post.Link),
+			fingerprint(post.Title, post.ContentText),
+			media.url,
+			media.mimeType,
+			media.medium,
+			media.size,
+			media.width,
+			media.height,
+			media.thumbnail,
+			normalizeTags(post.Tags),
+			post.PubTimeEstimated,
+		)
+		// Для только что добавленной новости обновление ничего не меняет:
+		// заголовок и содержание совпадают.
+		batch.Queue(updateNewsSQL,
+			post.Title,
+			post.Content,
+			post.GUID,
+			post.Source,
+			normalizeLink(post.Link),
+			fingerprint(post.Title, post.ContentText),
+			now,
+			post.ContentText,
+		)
+		queued = append(queued, i)
+	}
+	if len(queued) == 0 {
+		return results, nil
+	}
+
+	ctx := context.Background()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return results, err
+	}
+	defer tx.Rollback(ctx)
+
+	br := tx.SendBatch(ctx, batch)
+	for _, i := range queued {
+		err = br.QueryRow().Scan(&results[i].ID)
+		if err == nil {
+			results[i].Inserted = true
+		} else if !errors.Is(err, pgx.ErrNoRows) {
+			results[i].Err = err
+			br.Close()
+			return discardResults(results), err
+		}
+		var updatedID int
+		err = br.QueryRow().Scan(&updatedID)
+		if err == nil {
+			results[i].ID = updatedID
+			results[i].Updated = true
+		} else if !errors.Is(err, pgx.ErrNoRows) {
+			results[i].Err = err
+			br.Close()
+			return discardResults(results), err
+		}
+	}
+	if err = br.Close(); err != nil {
+		return discardResults(results), err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return discardResults(results), err
+	}
+	return results, nil
+}
+
+// discardResults сбрасывает результаты новостей пакета после отката транзакции:
+// новости, добавленные или обновлённые до ошибки, в БД не сохранились.
+func discardResults(results []AddResult) []AddResult {
+	for i := range results {
+		results[i].ID = 0
+		results[i].Inserted = false
+		results[i].Updated = false
+	}
+	return results
+}
+
+// Обновление ранее добавленной новости ($3 - guid, $4 - источник, $5 - нормализованная ссылка),
+// если изменились заголовок ($1) или содержание ($2, текстовая версия - $8).
+// Прежняя редакция сохраняется с временем замены $7. Обновление не выполняется,
+// если новый отпечаток ($6) совпадает с отпечатком другой новости.
+const updateNewsSQL = `
+		WITH old AS (
+			SELECT o.id, o.title, o.content, o.content_text
+			FROM news o
+			WHERE ((o.source = $4 AND o.guid = $3 AND $3 <> '') OR (o.link_norm = $5 AND $5 <> ''))
+				AND (o.title <> $1 OR o.content <> $2)
+				AND NOT EXISTS (SELECT 1 FROM news n WHERE n.fingerprint = $6 AND n.id <> o.id)
+			ORDER BY (o.guid = $3 AND $3 <> '') DESC
+			LIMIT 1
+			FOR UPDATE
+		), revision AS (
+			INSERT INTO news_revisions (news_id, title, content, content_text, revised_time)
+			SELECT id, title, content, content_text, $7 FROM old
+		)
+		UPDATE news
+		SET title = $1, content = $2, content_text = $8, fingerprint = $6, updated_time = $7
+		FROM old
+		WHERE news.id = old.id
+		RETURNING news.id;
+		`
+
+// NewsRevisions возвращает прежние редакции новости, начиная с самой ранней.
+func (s *Storage) NewsRevisions(news_id int, uniqueReqID string) ([]Revision, error) {
+	rows, err := s.db.Query(context.Background(), `
+		SELECT 
+			id,
+			news_id,
+			title,
+			content,
+			revised_time
+		FROM news_revisions
+		WHERE news_id=$1
+		ORDER BY id;
+	`, news_id,
+	)
+	if err != nil {
+		log.Printf("request_id %s: ошибка запроса в БД (редакции новости %d): %v", uniqueReqID, news_id, err)
+		return nil, err
+	}
+	var revisions []Revision
+	// итерирование по результату выполнения запроса
+	// и сканирование каждой строки в переменную
+	for rows.Next() {
+		var r Revision
+		err = rows.Scan(
+			&r.ID,
+			&r.NewsID,
+			&r.Title,
+			&r.Content,
+			&r.RevisedTime,
+		)
 		if err != nil {
-			return err
+			log.Printf("request_id %s: ошибка чтения полученных данных из БД (редакции новости %d): %v", uniqueReqID, news_id, err)
+			return nil, err
 		}
+		revisions = append(revisions, r)
+	}
+	return revisions, rows.Err()
+}
+
+// Результат проверки наличия новости.
+type NewsCheckResult struct {
+	ID     int    `json:"ID"`     // уникальный идентификатор новости
+	Source string `json:"Source"` // источник новости (хост RSS-ленты)
+}
+
+// NewsSource возвращает источник новости. Если новости нет в БД, возвращает false.
+func (s *Storage) NewsSource(news_id int, uniqueReqID string) (NewsCheckResult, bool, error) {
+	c := NewsCheckResult{ID: news_id}
+	err := s.db.QueryRow(context.Background(), `
+		SELECT 
+			source
+		FROM news
+		WHERE id=$1;
+	`, news_id,
+	).Scan(&c.Source)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c, false, nil
+	}
+	if err != nil {
+		log.Printf("request_id %s: ошибка запроса в БД (источник новости %d): %v", uniqueReqID, news_id, err)
+		return c, false, err
 	}
-	return nil
+	return c, true, nil
 }
 
 // NewsCheck проверяет наличие новости в БД.
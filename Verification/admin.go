@@ -0,0 +1,197 @@
+package main
+
+import (
+	"APIGateway/Verification/audit"
+	"APIGateway/Verification/dictionary"
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Токен администратора. Если не задан, административные методы отключены.
+var adminToken = os.Getenv("ADMIN_TOKEN")
+
+// Содержимое словаря.
+type DictionaryList struct {
+	Version int64             `json:"Version"` // версия словаря
+	Terms   []dictionary.Term `json:"Terms"`
+}
+
+// Число записей журнала проверок в ответе по умолчанию и наибольшее.
+const (
+	auditDefaultLimit = 100
+	auditMaxLimit     = 1000
+)
+
+// Записи журнала проверок, начиная с последних.
+type AuditList struct {
+	Entries []audit.Entry `json:"Entries"`
+}
+
+// Результат изменения словаря.
+type DictionaryChange struct {
+	Version int64 `json:"Version"` // версия словаря после изменения
+	Changed int   `json:"Changed"` // число добавленных, изменённых или удалённых слов
+}
+
+// admin пропускает запрос к обработчику только с токеном администратора
+// в заголовке "Authorization: Bearer <токен>".
+func admin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		uniqueReqID := r.URL.Query().Get("request_id")
+		if adminToken == "" {
+			log.Printf("request_id %s: административные методы отключены (не задан ADMIN_TOKEN)", uniqueReqID)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+			log.Printf("request_id %s: неверный токен администратора", uniqueReqID)
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// adminDictionary возвращает словарь, указанный в запросе: основной или теневой (dictionary=shadow).
+func adminDictionary(r *http.Request) *dictionary.Dictionary {
+	if r.URL.Query().Get("dictionary") == "shadow" {
+		return shadowDict
+	}
+	return dict
+}
+
+// список слов словаря
+func dictionaryList(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	version, terms := adminDictionary(r).Snapshot()
+	if terms == nil {
+		terms = []dictionary.Term{}
+	}
+	json.NewEncoder(w).Encode(DictionaryList{Version: version, Terms: terms})
+}
+
+// добавление слов в словарь: в теле запроса - массив слов с категорией и серьёзностью
+func dictionaryAdd(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	uniqueReqID := r.URL.Query().Get("request_id")
+
+	var terms []dictionary.Term
+	err := json.NewDecoder(r.Body).Decode(&terms)
+	if err != nil {
+		log.Printf("request_id %s: ошибка декодирования json: %v", uniqueReqID, err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	d := adminDictionary(r)
+	changed, err := d.Add(terms)
+	if err != nil {
+		log.Printf("request_id %s: слова не добавлены в словарь: %v", uniqueReqID, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("request_id %s: в словарь добавлено или изменено слов: %d", uniqueReqID, changed)
+	json.NewEncoder(w).Encode(DictionaryChange{Version: d.Version(), Changed: changed})
+}
+
+// удаление слов из словаря: в теле запроса - массив слов
+func dictionaryRemove(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	uniqueReqID := r.URL.Query().Get("request_id")
+
+	var terms []string
+	err := json.NewDecoder(r.Body).Decode(&terms)
+	if err != nil {
+		log.Printf("request_id %s: ошибка декодирования json: %v", uniqueReqID, err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	d := adminDictionary(r)
+	removed, err := d.Remove(terms)
+	if err != nil {
+		log.Printf("request_id %s: слова не удалены из словаря: %v", uniqueReqID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("request_id %s: из словаря удалено слов: %d", uniqueReqID, removed)
+	json.NewEncoder(w).Encode(DictionaryChange{Version: d.Version(), Changed: removed})
+}
+
+// замена основного словаря теневым
+func dictionaryPromote(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	uniqueReqID := r.URL.Query().Get("request_id")
+
+	version, terms := shadowDict.Snapshot()
+	count, err := dict.Replace(terms)
+	if err != nil {
+		log.Printf("request_id %s: основной словарь не заменён теневым: %v", uniqueReqID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("request_id %s: основной словарь заменён теневым (версия теневого %d), слов: %d", uniqueReqID, version, count)
+	json.NewEncoder(w).Encode(DictionaryChange{Version: dict.Version(), Changed: count})
+}
+
+// поиск в журнале проверок
+func auditList(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	uniqueReqID := r.URL.Query().Get("request_id")
+
+	q := audit.Query{
+		Author:       auditLog.HashAuthor(r.URL.Query().Get("author")),
+		Decision:     r.URL.Query().Get("decision"),
+		Reason:       r.URL.Query().Get("reason"),
+		ShadowDiffer: r.URL.Query().Get("shadow_differ") == "true",
+		Limit:        auditDefaultLimit,
+	}
+	for param, dst := range map[string]*int64{"from": &q.From, "to": &q.To} {
+		if v := r.URL.Query().Get(param); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				log.Printf("request_id %s: параметр %s в url %s: %v", uniqueReqID, param, v, err)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			*dst = n
+		}
+	}
+	for param, dst := range map[string]*int{"news_id": &q.NewsID, "limit": &q.Limit} {
+		if v := r.URL.Query().Get(param); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				log.Printf("request_id %s: параметр %s в url %s: %v", uniqueReqID, param, v, err)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			*dst = n
+		}
+	}
+	if q.Limit > auditMaxLimit {
+		q.Limit = auditMaxLimit
+	}
+
+	entries, err := auditLog.Find(q)
+	if err != nil {
+		log.Printf("request_id %s: ошибка чтения журнала проверок: %v", uniqueReqID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if entries == nil {
+		entries = []audit.Entry{}
+	}
+	json.NewEncoder(w).Encode(AuditList{Entries: entries})
+}
@@ -0,0 +1,258 @@
+// Пакет для журнала решений проверки комментариев.
+//
+// Журнал - файл JSONL, в который записи только добавляются. Автор комментария
+// (IP-адрес) хранится в виде HMAC-SHA256 с секретом журнала, поэтому записи
+// одного автора можно найти по его адресу, но адрес из журнала не восстанавливается.
+//
+// Записи содержат исходный текст комментария, поэтому размер журнала ограничен:
+// файл, достигший Options.MaxSize, переименовывается в <файл>.1 (прежние - в <файл>.2 и т.д.),
+// и хранится не больше Options.MaxFiles прежних файлов, более старые удаляются.
+package audit
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Решения проверки.
+const (
+	DecisionAllow  = "allow"  // комментарий принят
+	DecisionMask   = "mask"   // комментарий принят с замаскированными словами
+	DecisionReject = "reject" // комментарий отклонён
+)
+
+// Наибольшая длина строки журнала при чтении.
+const maxLine = 1 << 20
+
+// Запись журнала.
+type Entry struct {
+	Time              int64    `json:"Time"` // время проверки (Unix, секунды)
+	RequestID         string   `json:"RequestID"`
+	NewsID            int      `json:"NewsID"`
+	Source            string   `json:"Source,omitempty"` // источник новости
+	Author            string   `json:"Author,omitempty"` // хэш IP-адреса автора
+	Comment           string   `json:"Comment"`          // текст комментария
+	Policy            string   `json:"Policy"`           // политика проверки
+	Decision          string   `json:"Decision"`         // решение: allow, mask, reject
+	Reason            string   `json:"Reason,omitempty"` // код причины отклонения
+	Score             int      `json:"Score"`
+	Rules             []string `json:"Rules,omitempty"`   // сработавшие правила: слова словаря и проверки спама
+	DictionaryVersion int64    `json:"DictionaryVersion"` // версия словаря
+	Shadow            *Result  `json:"Shadow,omitempty"`  // результат проверки по теневому словарю
+}
+
+// Результат проверки по теневому словарю. Не влияет на решение.
+type Result struct {
+	DictionaryVersion int64    `json:"DictionaryVersion"`
+	Decision          string   `json:"Decision"`
+	Reason            string   `json:"Reason,omitempty"`
+	Score             int      `json:"Score"`
+	Rules             []string `json:"Rules,omitempty"`
+}
+
+// Условия поиска записей. Пустые поля не ограничивают поиск.
+type Query struct {
+	NewsID       int
+	Author       string // хэш IP-адреса автора (см. HashAuthor)
+	Decision     string
+	Reason       string
+	From, To     int64 // интервал времени [From, To] (Unix, секунды)
+	ShadowDiffer bool  // только записи, в которых решение по теневому словарю отличается
+	Limit        int   // наибольшее число записей
+}
+
+// Параметры ротации журнала.
+type Options struct {
+	MaxSize  int64 // наибольший размер файла журнала в байтах; 0 - без ротации
+	MaxFiles int   // число хранимых прежних файлов журнала
+}
+
+// Журнал.
+type Log struct {
+	mu     sync.Mutex
+	path   string
+	f      *os.File
+	size   int64 // размер текущего файла
+	opts   Options
+	secret []byte
+}
+
+// Open открывает файл журнала для добавления записей, создавая его при необходимости.
+// secret - секрет для хэширования адресов авторов.
+func Open(path, secret string, opts Options) (*Log, error) {
+	l := Log{path: path, opts: opts, secret: []byte(secret)}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// open открывает текущий файл журнала.
+func (l *Log) open() error {
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	l.f, l.size = f, info.Size()
+	return nil
+}
+
+// rotate переименовывает текущий файл журнала в прежний и открывает новый.
+func (l *Log) rotate() error {
+	if err := l.f.Close(); err != nil {
+		return err
+	}
+	err := os.Remove(l.backup(l.opts.MaxFiles))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for i := l.opts.MaxFiles - 1; i >= 1; i-- {
+		err = os.Rename(l.backup(i), l.backup(i+1))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if l.opts.MaxFiles > 0 {
+		err = os.Rename(l.path, l.backup(1))
+	} else {
+		err = os.Remove(l.path)
+	}
+	if err != nil {
+		return err
+	}
+	return l.open()
+}
+
+// backup возвращает путь прежнего файла журнала с номером n (1 - самый новый).
+func (l *Log) backup(n int) string {
+	return fmt.Sprintf("%s.%d", l.path, n)
+}
+
+// HashAuthor возвращает хэш IP-адреса автора. Для пустого адреса возвращает пустую строку.
+func (l *Log) HashAuthor(author string) string {
+	if author == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, l.secret)
+	mac.Write([]byte(author))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Append добавляет запись в журнал.
+func (l *Log) Append(e Entry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.opts.MaxSize > 0 && l.size > 0 && l.size+int64(len(b)) > l.opts.MaxSize {
+		if err = l.rotate(); err != nil {
+			return fmt.Errorf("ротация журнала: %v", err)
+		}
+	}
+	n, err := l.f.Write(b)
+	l.size += int64(n)
+	return err
+}
+
+// Find возвращает записи, удовлетворяющие условиям, начиная с последних.
+func (l *Log) Find(q Query) ([]Entry, error) {
+	if q.Limit <= 0 {
+		return nil, nil
+	}
+	files, err := l.files()
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		defer f.Close()
+	}
+
+	// Файлы журнала читаются целиком от старых к новым, последние Limit подходящих записей
+	// хранятся в кольцевом буфере.
+	var found []Entry
+	next := 0
+	for _, f := range files {
+		sc := bufio.NewScanner(f)
+		sc.Buffer(make([]byte, 64*1024), maxLine)
+		for sc.Scan() {
+			var e Entry
+			if json.Unmarshal(sc.Bytes(), &e) != nil {
+				// недописанная при аварийной остановке строка
+				continue
+			}
+			if !q.match(e) {
+				continue
+			}
+			if len(found) < q.Limit {
+				found = append(found, e)
+				continue
+			}
+			found[next] = e
+			next = (next + 1) % q.Limit
+		}
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+	}
+	out := make([]Entry, 0, len(found))
+	for i := len(found) - 1; i >= 0; i-- {
+		out = append(out, found[(next+i)%len(found)])
+	}
+	return out, nil
+}
+
+// files открывает прежние и текущий файлы журнала от старых к новым. Файлы открываются
+// под блокировкой, поэтому ротация во время чтения не влияет на результат.
+func (l *Log) files() ([]*os.File, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var files []*os.File
+	for i := l.opts.MaxFiles; i >= 0; i-- {
+		path := l.path
+		if i > 0 {
+			path = l.backup(i)
+		}
+		f, err := os.Open(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			for _, f := range files {
+				f.Close()
+			}
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+func (q Query) match(e Entry) bool {
+	switch {
+	case q.NewsID != 0 && e.NewsID != q.NewsID,
+		q.Author != "" && e.Author != q.Author,
+		q.Decision != "" && e.Decision != q.Decision,
+		q.Reason != "" && e.Reason != q.Reason,
+		q.From != 0 && e.Time < q.From,
+		q.To != 0 && e.Time > q.To,
+		q.ShadowDiffer && (e.Shadow == nil || e.Shadow.Decision == e.Decision):
+		return false
+	}
+	return true
+}
@@ -0,0 +1,331 @@
+// Пакет для работы с файлом конфигурации сервиса проверки комментариев.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// Действия при нахождении запрещённого слова.
+const (
+	ActionReject = "reject" // комментарий отклоняется
+	ActionMask   = "mask"   // комментарий публикуется, найденный фрагмент заменяется символами маски
+)
+
+// Символ маски по умолчанию.
+const DefaultMaskChar = "*"
+
+// Правила проверки комментария.
+const (
+	RuleBannedWords = "banned_words" // слова словаря (типы word и prefix)
+	RuleRegex       = "regex"        // регулярные выражения словаря
+	RuleSpam        = "spam"         // признаки спама (раздел spam, кроме max_length)
+	RuleLength      = "length"       // длина комментария (spam.max_length)
+	RuleDuplicate   = "duplicate"    // повторы и флуд (раздел duplicates)
+)
+
+// Режимы выполнения правил.
+const (
+	ModeScore        = "score"         // выполняются все правила, результаты и оценки суммируются
+	ModeShortCircuit = "short_circuit" // правила выполняются до первого отклонения комментария
+)
+
+// Имя политики по умолчанию.
+const DefaultPolicy = "default"
+
+// Структура конфигурационного файла. Поля pipeline, actions и default_action задают
+// политику проверки по умолчанию; политики из policies переопределяют её для отдельных
+// новостей и источников новостей.
+type Config struct {
+	Pipeline      Pipeline          `json:"pipeline"`             // правила проверки
+	Actions       map[string]string `json:"actions"`              // действие для категории слов словаря: reject или mask
+	DefaultAction string            `json:"default_action"`       // действие для категорий, не указанных в actions
+	MaskChar      string            `json:"mask_char,omitempty"`  // символ маски (по умолчанию *)
+	Spam          Spam              `json:"spam"`                 // проверки на спам
+	Duplicates    *Duplicates       `json:"duplicates,omitempty"` // проверка повторов и флуда; nil - отключена
+	Policies      []Policy          `json:"policies,omitempty"`   // политики для отдельных новостей и источников
+
+	def Policy // политика по умолчанию
+}
+
+// Конвейер правил проверки.
+type Pipeline struct {
+	Mode      string   `json:"mode,omitempty"`      // режим: score или short_circuit
+	Rules     []string `json:"rules,omitempty"`     // правила в порядке выполнения
+	Threshold int      `json:"threshold,omitempty"` // комментарий отклоняется при оценке не меньше порога; 0 - оценка не проверяется
+}
+
+// Политика проверки для новостей и источников новостей. Незаданные поля
+// (пустые режим и список правил, нулевой порог, отсутствующие действия)
+// берутся из политики по умолчанию.
+type Policy struct {
+	Name          string            `json:"name"`
+	NewsIDs       []int             `json:"news_ids,omitempty"` // новости, к которым применяется политика
+	Sources       []string          `json:"sources,omitempty"`  // источники новостей (хосты RSS-лент)
+	Pipeline      Pipeline          `json:"pipeline"`
+	Actions       map[string]string `json:"actions,omitempty"`
+	DefaultAction string            `json:"default_action,omitempty"`
+}
+
+// Проверка повторов и флуда за скользящее окно.
+type Duplicates struct {
+	Window      int `json:"window"`       // окно в секундах
+	MaxDistance int `json:"max_distance"` // наибольшее число различающихся бит отпечатков (simhash) повторов
+	MinLength   int `json:"min_length"`   // более короткие комментарии ("спасибо") не проверяются на повтор
+	FloodLimit  int `json:"flood_limit"`  // наибольшее число комментариев с одного IP-адреса за окно; 0 - без ограничения
+}
+
+// Проверки на спам. Каждая проверка добавляет к оценке комментария свой балл (Score);
+// проверка с нулевым баллом или без раздела в файле отключена.
+type Spam struct {
+	URLs           *URLLimit    `json:"urls,omitempty"`            // число ссылок
+	BlockedDomains *DomainList  `json:"blocked_domains,omitempty"` // ссылки на запрещённые домены
+	AllowedDomains []string     `json:"allowed_domains,omitempty"` // домены, ссылки на которые не учитываются
+	Caps           *CapsLimit   `json:"caps,omitempty"`            // доля заглавных букв
+	RepeatedChars  *RepeatLimit `json:"repeated_chars,omitempty"`  // повторы одного символа
+	Phrases        *PhraseList  `json:"phrases,omitempty"`         // известные спам-фразы
+	MaxLength      *LengthLimit `json:"max_length,omitempty"`      // длина комментария
+}
+
+// Ограничение числа ссылок: балл начисляется, если ссылок больше Max.
+type URLLimit struct {
+	Max   int `json:"max"`
+	Score int `json:"score"`
+}
+
+// Список доменов (с поддоменами): балл начисляется за каждую ссылку на домен из списка.
+type DomainList struct {
+	Domains []string `json:"domains"`
+	Score   int      `json:"score"`
+}
+
+// Ограничение доли заглавных букв: балл начисляется, если в комментарии не меньше MinLetters букв
+// и доля заглавных не меньше Ratio.
+type CapsLimit struct {
+	MinLetters int     `json:"min_letters"`
+	Ratio      float64 `json:"ratio"`
+	Score      int     `json:"score"`
+}
+
+// Ограничение повторов: балл начисляется за каждую серию одного символа длиннее Max.
+type RepeatLimit struct {
+	Max   int `json:"max"`
+	Score int `json:"score"`
+}
+
+// Спам-фразы: балл начисляется за каждое вхождение фразы (с той же нормализацией текста,
+// что и для запрещённых слов).
+type PhraseList struct {
+	Phrases []string `json:"phrases"`
+	Score   int      `json:"score"`
+}
+
+// Ограничение длины комментария в символах: балл начисляется, если длина больше Max.
+type LengthLimit struct {
+	Max   int `json:"max"`
+	Score int `json:"score"`
+}
+
+// Load читает и проверяет файл конфигурации.
+func Load(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Config
+	err = json.Unmarshal(b, &c)
+	if err != nil {
+		return nil, fmt.Errorf("конфигурация %s: %v", path, err)
+	}
+	err = c.validate()
+	if err != nil {
+		return nil, fmt.Errorf("конфигурация %s: %v", path, err)
+	}
+	return &c, nil
+}
+
+// Policy возвращает политику для новости: политику, в которой указана новость,
+// иначе политику источника новости, иначе политику по умолчанию.
+func (c *Config) Policy(newsID int, source string) *Policy {
+	for i := range c.Policies {
+		for _, id := range c.Policies[i].NewsIDs {
+			if id == newsID {
+				return &c.Policies[i]
+			}
+		}
+	}
+	source = normalizeSource(source)
+	if source != "" {
+		for i := range c.Policies {
+			for _, s := range c.Policies[i].Sources {
+				if s == source {
+					return &c.Policies[i]
+				}
+			}
+		}
+	}
+	return &c.def
+}
+
+// Action возвращает действие для категории слов словаря.
+func (p *Policy) Action(category string) string {
+	if a, ok := p.Actions[category]; ok {
+		return a
+	}
+	return p.DefaultAction
+}
+
+// Mask возвращает символ маски.
+func (c *Config) Mask() rune {
+	r, _ := utf8.DecodeRuneInString(c.MaskChar)
+	return r
+}
+
+// validate проверяет значения и задаёт значения по умолчанию.
+func (c *Config) validate() error {
+	if c.MaskChar == "" {
+		c.MaskChar = DefaultMaskChar
+	}
+	if utf8.RuneCountInString(c.MaskChar) != 1 {
+		return fmt.Errorf("mask_char %q должен быть одним символом", c.MaskChar)
+	}
+	if d := c.Duplicates; d != nil {
+		if d.Window <= 0 || d.MaxDistance < 0 || d.MaxDistance > 64 || d.MinLength < 0 || d.FloodLimit < 0 {
+			return fmt.Errorf("duplicates: window должно быть больше 0, max_distance - от 0 до 64, остальные значения неотрицательны")
+		}
+	}
+	err := c.Spam.validate()
+	if err != nil {
+		return err
+	}
+
+	// Политика по умолчанию.
+	if c.DefaultAction == "" {
+		c.DefaultAction = ActionReject
+	}
+	if c.Pipeline.Mode == "" {
+		c.Pipeline.Mode = ModeScore
+	}
+	if c.Pipeline.Rules == nil {
+		c.Pipeline.Rules = []string{RuleBannedWords, RuleRegex}
+		if c.Duplicates != nil {
+			c.Pipeline.Rules = append(c.Pipeline.Rules, RuleDuplicate)
+		}
+		c.Pipeline.Rules = append(c.Pipeline.Rules, RuleSpam, RuleLength)
+	}
+	c.def = Policy{Name: DefaultPolicy, Pipeline: c.Pipeline, Actions: c.Actions, DefaultAction: c.DefaultAction}
+	err = c.def.validate(c)
+	if err != nil {
+		return err
+	}
+	c.Actions = c.def.Actions
+
+	// Политики новостей и источников наследуют незаданные поля политики по умолчанию.
+	names := map[string]bool{DefaultPolicy: true}
+	for i := range c.Policies {
+		p := &c.Policies[i]
+		if p.Name == "" || names[p.Name] {
+			return fmt.Errorf("policies: пустое или повторяющееся имя политики %q", p.Name)
+		}
+		names[p.Name] = true
+		if p.Pipeline.Mode == "" {
+			p.Pipeline.Mode = c.def.Pipeline.Mode
+		}
+		if p.Pipeline.Rules == nil {
+			p.Pipeline.Rules = c.def.Pipeline.Rules
+		}
+		if p.Pipeline.Threshold == 0 {
+			p.Pipeline.Threshold = c.def.Pipeline.Threshold
+		}
+		actions := make(map[string]string, len(c.def.Actions)+len(p.Actions))
+		for category, action := range c.def.Actions {
+			actions[category] = action
+		}
+		for category, action := range p.Actions {
+			actions[category] = action
+		}
+		p.Actions = actions
+		if p.DefaultAction == "" {
+			p.DefaultAction = c.def.DefaultAction
+		}
+		for j, source := range p.Sources {
+			p.Sources[j] = normalizeSource(source)
+		}
+		err = p.validate(c)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate проверяет значения политики.
+func (p *Policy) validate(c *Config) error {
+	if !validAction(p.DefaultAction) {
+		return fmt.Errorf("политика %s: неизвестное действие default_action %q", p.Name, p.DefaultAction)
+	}
+	actions := make(map[string]string, len(p.Actions))
+	for category, action := range p.Actions {
+		if !validAction(action) {
+			return fmt.Errorf("политика %s: неизвестное действие %q для категории %q", p.Name, action, category)
+		}
+		// категории словаря хранятся в нижнем регистре
+		actions[strings.ToLower(strings.TrimSpace(category))] = action
+	}
+	p.Actions = actions
+
+	if p.Pipeline.Mode != ModeScore && p.Pipeline.Mode != ModeShortCircuit {
+		return fmt.Errorf("политика %s: неизвестный режим %q", p.Name, p.Pipeline.Mode)
+	}
+	if p.Pipeline.Threshold < 0 {
+		return fmt.Errorf("политика %s: отрицательный порог", p.Name)
+	}
+	seen := make(map[string]bool, len(p.Pipeline.Rules))
+	for _, rule := range p.Pipeline.Rules {
+		switch rule {
+		case RuleBannedWords, RuleRegex, RuleSpam, RuleLength:
+		case RuleDuplicate:
+			if c.Duplicates == nil {
+				return fmt.Errorf("политика %s: для правила duplicate нужен раздел duplicates", p.Name)
+			}
+		default:
+			return fmt.Errorf("политика %s: неизвестное правило %q", p.Name, rule)
+		}
+		if seen[rule] {
+			return fmt.Errorf("политика %s: правило %q указано дважды", p.Name, rule)
+		}
+		seen[rule] = true
+	}
+	return nil
+}
+
+// normalizeSource приводит источник новости к виду, в котором он хранится агрегатором.
+func normalizeSource(source string) string {
+	return strings.TrimPrefix(strings.ToLower(strings.TrimSpace(source)), "www.")
+}
+
+// validate проверяет значения проверок на спам.
+func (s *Spam) validate() error {
+	switch {
+	case s.URLs != nil && (s.URLs.Max < 0 || s.URLs.Score < 0):
+		return fmt.Errorf("spam.urls: отрицательное значение")
+	case s.BlockedDomains != nil && s.BlockedDomains.Score < 0:
+		return fmt.Errorf("spam.blocked_domains: отрицательное значение")
+	case s.Caps != nil && (s.Caps.MinLetters < 0 || s.Caps.Score < 0 || s.Caps.Ratio <= 0 || s.Caps.Ratio > 1):
+		return fmt.Errorf("spam.caps: ratio должно быть в интервале (0, 1], остальные значения неотрицательны")
+	case s.RepeatedChars != nil && (s.RepeatedChars.Max < 1 || s.RepeatedChars.Score < 0):
+		return fmt.Errorf("spam.repeated_chars: max должно быть больше 0")
+	case s.Phrases != nil && s.Phrases.Score < 0:
+		return fmt.Errorf("spam.phrases: отрицательное значение")
+	case s.MaxLength != nil && (s.MaxLength.Max < 1 || s.MaxLength.Score < 0):
+		return fmt.Errorf("spam.max_length: max должно быть больше 0")
+	}
+	return nil
+}
+
+func validAction(action string) bool {
+	return action == ActionReject || action == ActionMask
+}
@@ -0,0 +1,347 @@
+// Пакет для работы со словарём запрещённых слов.
+//
+// Словарь хранится в текстовом файле: одно правило на строку в формате
+// "слово;категория;серьёзность;тип". Категория, серьёзность и тип необязательны.
+// Пустые строки и строки, начинающиеся с #, пропускаются.
+package dictionary
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Категория и серьёзность слова по умолчанию.
+const (
+	DefaultCategory = "profanity"
+	DefaultSeverity = 1
+)
+
+// Типы правил словаря.
+const (
+	TypeWord   = "word"   // слово целиком (по умолчанию)
+	TypePrefix = "prefix" // слова, начинающиеся с основы слова: дурак - дурака, дураками
+	TypeRegex  = "regex"  // регулярное выражение (синтаксис RE2, без учёта регистра)
+	TypeAllow  = "allow"  // исключение: слово не считается запрещённым, даже если подходит под правило
+)
+
+// Правило словаря: запрещённое слово или исключение.
+type Term struct {
+	Term     string `json:"Term"`     // слово (в нижнем регистре) или регулярное выражение
+	Category string `json:"Category"` // категория: profanity, insult, politics и т.п.
+	Severity int    `json:"Severity"` // серьёзность нарушения
+	Type     string `json:"Type"`     // тип правила: word, prefix, regex, allow
+}
+
+// Словарь, связанный с файлом. Изменения через Add и Remove сохраняются в файл.
+type Dictionary struct {
+	mu      sync.RWMutex
+	path    string
+	terms   []Term // упорядочены по слову
+	version int64  // номер версии, увеличивается при каждом изменении состава словаря
+	modTime time.Time
+	size    int64
+}
+
+// Load читает словарь из файла.
+func Load(path string) (*Dictionary, error) {
+	d := Dictionary{path: path}
+	err := d.Reload()
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// Path возвращает путь к файлу словаря.
+func (d *Dictionary) Path() string {
+	return d.path
+}
+
+// Version возвращает номер текущей версии словаря.
+func (d *Dictionary) Version() int64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.version
+}
+
+// Snapshot возвращает номер версии и копию слов словаря.
+func (d *Dictionary) Snapshot() (int64, []Term) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.version, append([]Term(nil), d.terms...)
+}
+
+// Reload перечитывает файл словаря. Версия увеличивается, только если состав словаря изменился.
+func (d *Dictionary) Reload() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	info, err := os.Stat(d.path)
+	if err != nil {
+		return err
+	}
+	b, err := os.ReadFile(d.path)
+	if err != nil {
+		return err
+	}
+	terms, err := Parse(b)
+	if err != nil {
+		return fmt.Errorf("словарь %s: %v", d.path, err)
+	}
+	d.modTime, d.size = info.ModTime(), info.Size()
+	if d.version == 0 || !equal(d.terms, terms) {
+		d.terms = terms
+		d.version++
+	}
+	return nil
+}
+
+// Watch проверяет файл словаря с периодом interval и перечитывает его при изменении.
+// Ошибки чтения пишутся в канал; при ошибке используется прежняя версия словаря.
+func (d *Dictionary) Watch(interval time.Duration, errs chan<- error) {
+	for {
+		time.Sleep(interval)
+		info, err := os.Stat(d.path)
+		if err != nil {
+			errs <- fmt.Errorf("словарь %s: %v", d.path, err)
+			continue
+		}
+		d.mu.RLock()
+		changed := !info.ModTime().Equal(d.modTime) || info.Size() != d.size
+		d.mu.RUnlock()
+		if !changed {
+			continue
+		}
+		err = d.Reload()
+		if err != nil {
+			errs <- err
+		}
+	}
+}
+
+// Add добавляет слова в словарь или обновляет категорию и серьёзность уже имеющихся.
+// Возвращает число добавленных или изменённых слов.
+func (d *Dictionary) Add(add []Term) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	byTerm := make(map[string]Term, len(d.terms))
+	for _, t := range d.terms {
+		byTerm[t.Term] = t
+	}
+	var changed int
+	for _, t := range add {
+		t, err := normalize(t)
+		if err != nil {
+			return 0, err
+		}
+		if old, ok := byTerm[t.Term]; !ok || old != t {
+			byTerm[t.Term] = t
+			changed++
+		}
+	}
+	if changed == 0 {
+		return 0, nil
+	}
+	terms := make([]Term, 0, len(byTerm))
+	for _, t := range byTerm {
+		terms = append(terms, t)
+	}
+	return changed, d.save(terms)
+}
+
+// Remove удаляет слова из словаря. Возвращает число удалённых слов.
+func (d *Dictionary) Remove(remove []string) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	skip := make(map[string]bool, len(remove))
+	for _, term := range remove {
+		// регулярные выражения хранятся без изменения регистра
+		skip[strings.TrimSpace(term)] = true
+		skip[strings.ToLower(strings.TrimSpace(term))] = true
+	}
+	var terms []Term
+	for _, t := range d.terms {
+		if !skip[t.Term] {
+			terms = append(terms, t)
+		}
+	}
+	removed := len(d.terms) - len(terms)
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, d.save(terms)
+}
+
+// Replace заменяет все слова словаря (например, словами теневого словаря).
+// Возвращает число слов словаря после замены.
+func (d *Dictionary) Replace(terms []Term) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	byTerm := make(map[string]Term, len(terms))
+	for _, t := range terms {
+		t, err := normalize(t)
+		if err != nil {
+			return 0, err
+		}
+		byTerm[t.Term] = t
+	}
+	replaced := make([]Term, 0, len(byTerm))
+	for _, t := range byTerm {
+		replaced = append(replaced, t)
+	}
+	sortTerms(replaced)
+	if equal(d.terms, replaced) {
+		return len(replaced), nil
+	}
+	return len(replaced), d.save(replaced)
+}
+
+// save записывает словарь в файл через временный файл и делает его текущей версией.
+// Вызывается под блокировкой d.mu.
+func (d *Dictionary) save(terms []Term) error {
+	sortTerms(terms)
+	tmp := d.path + ".tmp"
+	err := os.WriteFile(tmp, Format(terms), 0644)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmp, d.path)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(d.path)
+	if err != nil {
+		return err
+	}
+	d.modTime, d.size = info.ModTime(), info.Size()
+	d.terms = terms
+	d.version++
+	return nil
+}
+
+// Parse разбирает словарь в текстовом формате.
+func Parse(b []byte) ([]Term, error) {
+	seen := make(map[string]int)
+	var terms []Term
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, ";")
+		if len(fields) > 4 {
+			return nil, fmt.Errorf("строка %d: больше четырёх полей", n)
+		}
+		t := Term{Term: fields[0]}
+		if len(fields) > 1 {
+			t.Category = fields[1]
+		}
+		if len(fields) > 2 && strings.TrimSpace(fields[2]) != "" {
+			severity, err := strconv.Atoi(strings.TrimSpace(fields[2]))
+			if err != nil {
+				return nil, fmt.Errorf("строка %d: серьёзность %q: %v", n, fields[2], err)
+			}
+			t.Severity = severity
+		}
+		if len(fields) > 3 {
+			t.Type = fields[3]
+		}
+		t, err := normalize(t)
+		if err != nil {
+			return nil, fmt.Errorf("строка %d: %v", n, err)
+		}
+		// Повтор слова заменяет прежнее описание.
+		if i, ok := seen[t.Term]; ok {
+			terms[i] = t
+			continue
+		}
+		seen[t.Term] = len(terms)
+		terms = append(terms, t)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	sortTerms(terms)
+	return terms, nil
+}
+
+// Format записывает словарь в текстовом формате.
+func Format(terms []Term) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("# слово;категория;серьёзность;тип\n")
+	for _, t := range terms {
+		fmt.Fprintf(&buf, "%s;%s;%d;%s\n", t.Term, t.Category, t.Severity, t.Type)
+	}
+	return buf.Bytes()
+}
+
+// normalize приводит слово к нижнему регистру и задаёт значения по умолчанию.
+func normalize(t Term) (Term, error) {
+	t.Type = strings.ToLower(strings.TrimSpace(t.Type))
+	if t.Type == "" {
+		t.Type = TypeWord
+	}
+	switch t.Type {
+	case TypeWord, TypePrefix, TypeAllow:
+		t.Term = strings.ToLower(strings.TrimSpace(t.Term))
+	case TypeRegex:
+		// в регулярном выражении регистр значим (\S, \W); сравнение выполняется без учёта регистра
+		t.Term = strings.TrimSpace(t.Term)
+		_, err := Compile(t.Term)
+		if err != nil {
+			return t, fmt.Errorf("регулярное выражение %q: %v", t.Term, err)
+		}
+	default:
+		return t, fmt.Errorf("неизвестный тип правила %q", t.Type)
+	}
+	t.Category = strings.ToLower(strings.TrimSpace(t.Category))
+	if t.Term == "" {
+		return t, fmt.Errorf("пустое слово")
+	}
+	if strings.ContainsAny(t.Term, ";\n") || strings.ContainsAny(t.Category, ";\n") {
+		return t, fmt.Errorf("слово или категория %q содержит ';' или перевод строки", t.Term)
+	}
+	if t.Category == "" {
+		t.Category = DefaultCategory
+	}
+	if t.Severity == 0 {
+		t.Severity = DefaultSeverity
+	}
+	if t.Severity < 0 {
+		return t, fmt.Errorf("отрицательная серьёзность слова %q", t.Term)
+	}
+	return t, nil
+}
+
+// Compile компилирует регулярное выражение правила типа regex.
+func Compile(expr string) (*regexp.Regexp, error) {
+	return regexp.Compile("(?i)" + expr)
+}
+
+func sortTerms(terms []Term) {
+	sort.Slice(terms, func(i, j int) bool { return terms[i].Term < terms[j].Term })
+}
+
+func equal(a, b []Term) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,249 @@
+// Пакет для обнаружения повторных комментариев и флуда.
+//
+// Хранилище держит отпечатки (simhash) комментариев за скользящее окно времени
+// отдельно для каждой новости и для каждого автора (IP-адреса). Комментарий считается
+// повтором, если за окно к той же новости или от того же автора был сохранён комментарий
+// с отпечатком, отличающимся не больше чем на MaxDistance бит. Флуд - число
+// комментариев автора за окно (включая отклонённые) не меньше FloodLimit.
+//
+// Проверка и запись комментария выполняются атомарно (CheckAndRecord), поэтому из
+// одновременно отправленных одинаковых комментариев проходит только один. Принятый
+// проверкой комментарий учитывается как ожидающий сохранения, пока сохранение не
+// подтверждено (Confirm), но не дольше PendingTimeout: несохранённый комментарий
+// можно отправить повторно.
+package duplicate
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Наибольшее число записей для одной новости или одного автора.
+// Более старые записи вытесняются, чтобы память была ограничена и при атаке.
+const maxEntries = 1000
+
+// Время, в течение которого принятый проверкой комментарий без подтверждения
+// сохранения учитывается при поиске повторов.
+const PendingTimeout = time.Minute
+
+// Результат проверки.
+const (
+	OK        = iota // не повтор и не флуд
+	Duplicate        // повтор недавнего комментария
+	Flood            // слишком много комментариев автора за окно
+)
+
+// Состояние записи о комментарии.
+const (
+	statePending  = iota // комментарий принят проверкой, сохранение не подтверждено
+	stateAccepted        // сохранение комментария подтверждено
+	stateRejected        // комментарий отклонён или не сохранён; учитывается только для флуда
+)
+
+// Параметры проверки.
+type Options struct {
+	Window      time.Duration // скользящее окно
+	MaxDistance int           // наибольшее расстояние Хэмминга между отпечатками повторов
+	MinLength   int           // комментарии короче (в символах нормализованного текста) не проверяются на повтор
+	FloodLimit  int           // наибольшее число комментариев автора за окно; 0 - без ограничения
+}
+
+// Запись о комментарии.
+type entry struct {
+	id    string // идентификатор проверки
+	at    time.Time
+	hash  uint64
+	state int
+}
+
+// Проверка, ожидающая подтверждения сохранения комментария.
+type check struct {
+	at     time.Time
+	newsID int
+	author string
+	result int
+}
+
+// Хранилище отпечатков.
+type Store struct {
+	mu       sync.Mutex
+	opts     Options
+	byNews   map[int][]entry
+	byAuthor map[string][]entry
+	checks   map[string]check // проверки по идентификаторам
+}
+
+// New создаёт хранилище.
+func New(opts Options) *Store {
+	return &Store{
+		opts:     opts,
+		byNews:   make(map[int][]entry),
+		byAuthor: make(map[string][]entry),
+		checks:   make(map[string]check),
+	}
+}
+
+// CheckAndRecord проверяет нормализованный текст комментария к новости newsID от автора author
+// (пустой author - автор неизвестен, проверки по автору не выполняются) и запоминает комментарий
+// под идентификатором проверки id. Комментарий, не являющийся повтором или флудом, ожидает
+// подтверждения сохранения. Повторный вызов с тем же id возвращает прежний результат.
+func (s *Store) CheckAndRecord(id string, newsID int, author, text string, now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.checks[id]; ok {
+		return c.result
+	}
+	since := now.Add(-s.opts.Window)
+	news := prune(s.byNews[newsID], since)
+	var byAuthor []entry
+	if author != "" {
+		byAuthor = prune(s.byAuthor[author], since)
+	}
+
+	h := Simhash(text)
+	result := OK
+	switch {
+	case author != "" && s.opts.FloodLimit > 0 && len(byAuthor) >= s.opts.FloodLimit:
+		result = Flood
+	case len([]rune(text)) < s.opts.MinLength:
+	case s.near(news, h, now) || s.near(byAuthor, h, now):
+		result = Duplicate
+	}
+
+	e := entry{id: id, at: now, hash: h, state: statePending}
+	if result != OK {
+		e.state = stateRejected
+	} else {
+		news = push(news, e)
+	}
+	s.byNews[newsID] = news
+	if author != "" {
+		s.byAuthor[author] = push(byAuthor, e)
+	}
+	s.checks[id] = check{at: now, newsID: newsID, author: author, result: result}
+	return result
+}
+
+// Confirm завершает проверку id: сохранённый комментарий (stored) учитывается при поиске
+// повторов до конца окна, несохранённый или отклонённый другими правилами - только для флуда.
+// Неизвестная или уже завершённая проверка пропускается.
+func (s *Store) Confirm(id string, stored bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.checks[id]
+	if !ok {
+		return
+	}
+	delete(s.checks, id)
+	state := stateRejected
+	if stored && c.result == OK {
+		state = stateAccepted
+	}
+	setState(s.byNews[c.newsID], id, state)
+	if c.author != "" {
+		setState(s.byAuthor[c.author], id, state)
+	}
+}
+
+// Cleanup удаляет устаревшие записи всех новостей и авторов. Вызывается периодически,
+// чтобы не копились записи новостей и авторов, от которых больше нет комментариев.
+func (s *Store) Cleanup(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	since := now.Add(-s.opts.Window)
+	for id, c := range s.checks {
+		if now.Sub(c.at) >= PendingTimeout {
+			delete(s.checks, id)
+		}
+	}
+	for id, entries := range s.byNews {
+		if entries = prune(entries, since); len(entries) == 0 {
+			delete(s.byNews, id)
+		} else {
+			s.byNews[id] = entries
+		}
+	}
+	for author, entries := range s.byAuthor {
+		if entries = prune(entries, since); len(entries) == 0 {
+			delete(s.byAuthor, author)
+		} else {
+			s.byAuthor[author] = entries
+		}
+	}
+}
+
+// near сообщает, что среди сохранённых или ожидающих сохранения комментариев есть близкий по отпечатку.
+func (s *Store) near(entries []entry, h uint64, now time.Time) bool {
+	for _, e := range entries {
+		counted := e.state == stateAccepted || (e.state == statePending && now.Sub(e.at) < PendingTimeout)
+		if counted && bits.OnesCount64(e.hash^h) <= s.opts.MaxDistance {
+			return true
+		}
+	}
+	return false
+}
+
+// setState меняет состояние записи проверки id.
+func setState(entries []entry, id string, state int) {
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].id == id {
+			entries[i].state = state
+			return
+		}
+	}
+}
+
+// prune удаляет записи старше since. Записи упорядочены по времени.
+func prune(entries []entry, since time.Time) []entry {
+	i := 0
+	for i < len(entries) && entries[i].at.Before(since) {
+		i++
+	}
+	return entries[i:]
+}
+
+// push добавляет запись, вытесняя самую старую при превышении maxEntries.
+func push(entries []entry, e entry) []entry {
+	if len(entries) >= maxEntries {
+		entries = entries[len(entries)-maxEntries+1:]
+	}
+	return append(entries, e)
+}
+
+// Simhash вычисляет 64-битный отпечаток текста по словам и парам соседних слов.
+// У близких текстов отпечатки отличаются в небольшом числе бит.
+func Simhash(text string) uint64 {
+	words := strings.Fields(text)
+	var v [64]int
+	add := func(feature string) {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		for i := 0; i < 64; i++ {
+			if sum&(1<<i) != 0 {
+				v[i]++
+			} else {
+				v[i]--
+			}
+		}
+	}
+	for i, w := range words {
+		add(w)
+		if i > 0 {
+			add(words[i-1] + " " + w)
+		}
+	}
+	var out uint64
+	for i := 0; i < 64; i++ {
+		if v[i] > 0 {
+			out |= 1 << i
+		}
+	}
+	return out
+}
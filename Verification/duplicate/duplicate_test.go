@@ -0,0 +1,92 @@
+package duplicate
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testComment = "это довольно длинный комментарий к новости"
+
+func testStore() *Store {
+	return New(Options{Window: 10 * time.Minute, MaxDistance: 8, MinLength: 5})
+}
+
+func TestStore_CheckAndRecord_concurrent(t *testing.T) {
+	s := testStore()
+	now := time.Now()
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	passed := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := strconv.Itoa(i)
+			if s.CheckAndRecord(id, 1, "10.0.0."+id, testComment, now) == OK {
+				mu.Lock()
+				passed++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+	if passed != 1 {
+		t.Errorf("принято одновременных одинаковых комментариев: %d, want 1", passed)
+	}
+}
+
+func TestStore_Confirm(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name   string
+		stored bool          // результат сохранения первого комментария
+		skip   bool          // подтверждение не отправляется
+		after  time.Duration // время повтора после первого комментария
+		want   int
+	}{
+		{name: "ожидает сохранения", skip: true, after: time.Second, want: Duplicate},
+		{name: "сохранён", stored: true, after: 5 * time.Minute, want: Duplicate},
+		{name: "не сохранён", stored: false, after: time.Second, want: OK},
+		{name: "нет подтверждения дольше PendingTimeout", skip: true, after: PendingTimeout, want: OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := testStore()
+			if got := s.CheckAndRecord("a", 1, "10.0.0.1", testComment, now); got != OK {
+				t.Fatalf("CheckAndRecord() первого комментария = %d, want %d", got, OK)
+			}
+			if !tt.skip {
+				s.Confirm("a", tt.stored)
+			}
+			if got := s.CheckAndRecord("b", 1, "10.0.0.2", testComment, now.Add(tt.after)); got != tt.want {
+				t.Errorf("CheckAndRecord() повтора = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStore_CheckAndRecord_sameID(t *testing.T) {
+	s := testStore()
+	now := time.Now()
+	for i := 0; i < 2; i++ {
+		if got := s.CheckAndRecord("a", 1, "10.0.0.1", testComment, now); got != OK {
+			t.Errorf("CheckAndRecord() вызов %d = %d, want %d", i+1, got, OK)
+		}
+	}
+}
+
+func TestStore_flood(t *testing.T) {
+	s := New(Options{Window: 10 * time.Minute, MaxDistance: 8, MinLength: 5, FloodLimit: 2})
+	now := time.Now()
+	want := []int{OK, OK, Flood}
+	for i, w := range want {
+		// разные комментарии одного автора к разным новостям
+		text := testComment + " " + strconv.Itoa(i*1000003)
+		if got := s.CheckAndRecord(strconv.Itoa(i), i, "10.0.0.1", text, now); got != w {
+			t.Errorf("CheckAndRecord() комментарий %d = %d, want %d", i+1, got, w)
+		}
+		s.Confirm(strconv.Itoa(i), false)
+	}
+}
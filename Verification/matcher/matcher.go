@@ -0,0 +1,150 @@
+// Пакет для поиска вхождений множества слов в тексте алгоритмом Ахо-Корасик.
+//
+// Автомат строится один раз для набора слов, после чего поиск выполняется
+// за один проход по тексту: время поиска линейно по длине текста
+// (плюс число найденных вхождений) и не зависит от размера словаря.
+package matcher
+
+// Вхождение слова в текст. Позиции - в символах (рунах) текста.
+type Match struct {
+	Pattern int // индекс слова в наборе, переданном в New
+	Start   int // позиция первого символа вхождения
+	End     int // позиция за последним символом вхождения
+}
+
+// Узел автомата (бора с суффиксными ссылками).
+type node struct {
+	next   map[rune]int32 // переходы по символу
+	fail   int32          // суффиксная ссылка: самый длинный собственный суффикс, имеющийся в боре
+	output int32          // ближайший по суффиксным ссылкам узел, которым заканчивается слово; -1 - нет
+	word   int32          // индекс слова, заканчивающегося в узле; -1 - нет
+	depth  int32          // длина пути от корня (в символах)
+}
+
+// Автомат для поиска вхождений набора слов. После построения используется
+// только для чтения и безопасен для одновременного использования.
+type Matcher struct {
+	nodes    []node
+	patterns int
+}
+
+// New строит автомат для набора слов. Пустые слова пропускаются,
+// для повторяющихся слов вхождение сообщается по первому из них.
+func New(patterns []string) *Matcher {
+	m := Matcher{nodes: []node{newNode(0)}, patterns: len(patterns)}
+
+	// Бор из всех слов.
+	for i, p := range patterns {
+		if p == "" {
+			continue
+		}
+		cur := int32(0)
+		for _, r := range p {
+			nxt, ok := m.nodes[cur].next[r]
+			if !ok {
+				nxt = int32(len(m.nodes))
+				m.nodes = append(m.nodes, newNode(m.nodes[cur].depth+1))
+				if m.nodes[cur].next == nil {
+					m.nodes[cur].next = make(map[rune]int32)
+				}
+				m.nodes[cur].next[r] = nxt
+			}
+			cur = nxt
+		}
+		if m.nodes[cur].word < 0 {
+			m.nodes[cur].word = int32(i)
+		}
+	}
+
+	// Суффиксные ссылки и ссылки на слова строятся обходом в ширину:
+	// к моменту обработки узла ссылки всех более коротких узлов уже известны.
+	queue := make([]int32, 0, len(m.nodes))
+	for _, child := range m.nodes[0].next {
+		m.nodes[child].fail = 0
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for r, child := range m.nodes[cur].next {
+			f := m.nodes[cur].fail
+			for {
+				if nxt, ok := m.nodes[f].next[r]; ok && nxt != child {
+					m.nodes[child].fail = nxt
+					break
+				}
+				if f == 0 {
+					m.nodes[child].fail = 0
+					break
+				}
+				f = m.nodes[f].fail
+			}
+			fail := m.nodes[child].fail
+			if m.nodes[fail].word >= 0 {
+				m.nodes[child].output = fail
+			} else {
+				m.nodes[child].output = m.nodes[fail].output
+			}
+			queue = append(queue, child)
+		}
+	}
+	return &m
+}
+
+func newNode(depth int32) node {
+	return node{fail: 0, output: -1, word: -1, depth: depth}
+}
+
+// Len возвращает число слов, для которых построен автомат.
+func (m *Matcher) Len() int {
+	return m.patterns
+}
+
+// FindAll возвращает все вхождения слов в текст, включая перекрывающиеся,
+// в порядке окончания вхождений.
+func (m *Matcher) FindAll(text []rune) []Match {
+	var matches []Match
+	m.scan(text, func(match Match) bool {
+		matches = append(matches, match)
+		return true
+	})
+	return matches
+}
+
+// Contains сообщает, входит ли в текст хотя бы одно слово.
+func (m *Matcher) Contains(text []rune) bool {
+	found := false
+	m.scan(text, func(Match) bool {
+		found = true
+		return false
+	})
+	return found
+}
+
+// scan проходит по тексту и вызывает fn для каждого вхождения, пока fn возвращает true.
+func (m *Matcher) scan(text []rune, fn func(Match) bool) {
+	cur := int32(0)
+	for i, r := range text {
+		for {
+			if nxt, ok := m.nodes[cur].next[r]; ok {
+				cur = nxt
+				break
+			}
+			if cur == 0 {
+				break
+			}
+			cur = m.nodes[cur].fail
+		}
+		out := cur
+		if m.nodes[out].word < 0 {
+			out = m.nodes[out].output
+		}
+		for out >= 0 {
+			n := m.nodes[out]
+			if !fn(Match{Pattern: int(n.word), Start: i + 1 - int(n.depth), End: i + 1}) {
+				return
+			}
+			out = n.output
+		}
+	}
+}
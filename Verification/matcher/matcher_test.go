@@ -0,0 +1,194 @@
+package matcher
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindAll(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		text     string
+		want     []Match
+	}{
+		{
+			name:     "перекрывающиеся слова",
+			patterns: []string{"he", "she", "his", "hers"},
+			text:     "ushers",
+			want: []Match{
+				{Pattern: 1, Start: 1, End: 4},
+				{Pattern: 0, Start: 2, End: 4},
+				{Pattern: 3, Start: 2, End: 6},
+			},
+		},
+		{
+			name:     "слово внутри другого слова",
+			patterns: []string{"abc", "b"},
+			text:     "xabcx",
+			want: []Match{
+				{Pattern: 1, Start: 2, End: 3},
+				{Pattern: 0, Start: 1, End: 4},
+			},
+		},
+		{
+			name:     "повторяющиеся слова сообщаются по первому",
+			patterns: []string{"abc", "abc"},
+			text:     "abcabc",
+			want: []Match{
+				{Pattern: 0, Start: 0, End: 3},
+				{Pattern: 0, Start: 3, End: 6},
+			},
+		},
+		{
+			name:     "пустое слово пропускается",
+			patterns: []string{"", "a"},
+			text:     "bab",
+			want: []Match{
+				{Pattern: 1, Start: 1, End: 2},
+			},
+		},
+		{
+			name:     "позиции в рунах для кириллицы",
+			patterns: []string{"дурак", "рак"},
+			text:     "ты дурак!",
+			want: []Match{
+				{Pattern: 0, Start: 3, End: 8},
+				{Pattern: 1, Start: 5, End: 8},
+			},
+		},
+		{
+			name:     "повторы символа",
+			patterns: []string{"aa"},
+			text:     "aaaa",
+			want: []Match{
+				{Pattern: 0, Start: 0, End: 2},
+				{Pattern: 0, Start: 1, End: 3},
+				{Pattern: 0, Start: 2, End: 4},
+			},
+		},
+		{
+			name:     "нет вхождений",
+			patterns: []string{"qwerty"},
+			text:     "qwert qwertx",
+			want:     nil,
+		},
+		{
+			name:     "пустой набор слов",
+			patterns: nil,
+			text:     "qwerty",
+			want:     nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New(tt.patterns)
+			if got := m.Len(); got != len(tt.patterns) {
+				t.Errorf("Len() = %d, want %d", got, len(tt.patterns))
+			}
+			got := m.FindAll([]rune(tt.text))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindAll(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContains(t *testing.T) {
+	m := New([]string{"he", "she", "hers"})
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{text: "ushers", want: true},
+		{text: "hers", want: true},
+		{text: "sh", want: false},
+		{text: "", want: false},
+	}
+	for _, tt := range tests {
+		if got := m.Contains([]rune(tt.text)); got != tt.want {
+			t.Errorf("Contains(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestContains_stopsEarly(t *testing.T) {
+	m := New([]string{"he", "she", "hers"})
+	// В тексте 3 перекрывающихся вхождения, поиск должен остановиться на первом.
+	calls := 0
+	m.scan([]rune("ushers"), func(Match) bool {
+		calls++
+		return false
+	})
+	if calls != 1 {
+		t.Errorf("scan() вызвал fn %d раз после отказа, want 1", calls)
+	}
+}
+
+// Буквы для случайных слов словаря и текста.
+var benchAlphabet = []rune("абвгдеёжзийклмнопрстуфхцчшщъыьэюя")
+
+// randomWord возвращает случайное слово длиной от minLen до maxLen символов.
+func randomWord(rnd *rand.Rand, minLen, maxLen int) string {
+	n := minLen + rnd.Intn(maxLen-minLen+1)
+	r := make([]rune, n)
+	for i := range r {
+		r[i] = benchAlphabet[rnd.Intn(len(benchAlphabet))]
+	}
+	return string(r)
+}
+
+// benchText возвращает текст не короче n символов из случайных слов,
+// среди которых примерно каждое двадцатое - слово словаря.
+func benchText(rnd *rand.Rand, n int, dict []string) []rune {
+	var b strings.Builder
+	for runes := 0; runes < n; {
+		w := randomWord(rnd, 2, 10)
+		if rnd.Intn(20) == 0 {
+			w = dict[rnd.Intn(len(dict))]
+		}
+		b.WriteString(w)
+		b.WriteByte(' ')
+		runes += len([]rune(w)) + 1
+	}
+	return []rune(b.String())[:n]
+}
+
+func BenchmarkFindAll(b *testing.B) {
+	texts := []struct {
+		name string
+		n    int
+	}{
+		{"1k", 1_000},
+		{"10k", 10_000},
+		{"100k", 100_000},
+	}
+	dicts := []struct {
+		name string
+		n    int
+	}{
+		{"10", 10},
+		{"1k", 1_000},
+		{"100k", 100_000},
+	}
+	for _, d := range dicts {
+		rnd := rand.New(rand.NewSource(1))
+		words := make([]string, d.n)
+		for i := range words {
+			words[i] = randomWord(rnd, 4, 12)
+		}
+		m := New(words)
+		for _, tt := range texts {
+			text := benchText(rnd, tt.n, words)
+			b.Run(fmt.Sprintf("text=%s/dict=%s", tt.name, d.name), func(b *testing.B) {
+				b.SetBytes(int64(len(string(text))))
+				for i := 0; i < b.N; i++ {
+					m.FindAll(text)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,247 @@
+// Пакет для нормализации текста комментария перед поиском запрещённых слов.
+//
+// Нормализация устраняет типичные способы обхода проверки:
+//   - совместимые и стилизованные символы (полноширинные, математические, лигатуры) - Unicode NFKC;
+//   - диакритические знаки и регистр;
+//   - смешение похожих кириллических, греческих и латинских букв ("qwеrty" с кириллической е);
+//   - замену букв цифрами и знаками ("qw3rty", "@" вместо "a");
+//   - вставку разделителей между буквами ("q.w.e.r.t.y", "q w e r t y");
+//   - повтор букв ("qwwweerty").
+//
+// Слова словаря нормализуются той же функцией, поэтому сравнение выполняется
+// в едином "скелетном" алфавите. Для каждого символа нормализованного текста
+// известна позиция исходных символов, из которых он получен.
+package normalize
+
+import (
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Похожие буквы приводятся к латинским (после перевода в нижний регистр).
+var homoglyphs = map[rune]rune{
+	// кириллица
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o',
+	'р': 'p', 'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'і': 'i', 'ї': 'i', 'ј': 'j',
+	'ѕ': 's', 'ԁ': 'd', 'ԛ': 'q', 'ԝ': 'w', 'ү': 'y', 'һ': 'h',
+	// греческий алфавит
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'n', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o',
+	'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x', 'ω': 'w',
+}
+
+// Цифры и знаки, которыми заменяют буквы. Заменяются, только если стоят рядом с буквой,
+// чтобы не искажать обычные числа.
+var leet = map[rune]rune{
+	'0': 'o', '1': 'i', '3': 'e', '4': 'a', '5': 's', '6': 'b', '7': 't', '8': 'b',
+	'@': 'a', '$': 's', '!': 'i', '|': 'i', '€': 'e',
+}
+
+// Виды символов промежуточного представления.
+const (
+	kindLetter = iota // буква
+	kindDigit         // цифра
+	kindLeet          // цифра или знак, возможно заменяющие букву
+	kindSpace         // пробел между словами
+)
+
+// Символ нормализованного текста и позиции исходных символов [start, end).
+type item struct {
+	r          rune
+	kind       int
+	start, end int
+}
+
+// Нормализованный текст.
+type Text struct {
+	Runes []rune // нормализованный текст; слова разделены одним пробелом
+	start []int  // позиция первого исходного символа для каждого символа Runes
+	end   []int  // позиция за последним исходным символом для каждого символа Runes
+}
+
+// String возвращает нормализованный текст строкой.
+func (t Text) String() string {
+	return string(t.Runes)
+}
+
+// Span возвращает позиции исходного текста (в символах), соответствующие
+// фрагменту нормализованного текста Runes[start:end].
+func (t Text) Span(start, end int) (int, int) {
+	if start >= end || end > len(t.Runes) {
+		return 0, 0
+	}
+	return t.start[start], t.end[end-1]
+}
+
+// Term нормализует слово словаря.
+func Term(s string) string {
+	return Normalize(s).String()
+}
+
+// Normalize нормализует текст.
+func Normalize(s string) Text {
+	items := fold(s)
+	items = trimSigns(items)
+	items = joinSpelledOut(items)
+	items = resolveLeet(items)
+	items = collapse(items)
+
+	t := Text{
+		Runes: make([]rune, 0, len(items)),
+		start: make([]int, 0, len(items)),
+		end:   make([]int, 0, len(items)),
+	}
+	for _, it := range items {
+		t.Runes = append(t.Runes, it.r)
+		t.start = append(t.start, it.start)
+		t.end = append(t.end, it.end)
+	}
+	return t
+}
+
+// fold приводит каждый исходный символ к NFKC, удаляет диакритику, переводит в нижний регистр
+// и заменяет похожие буквы латинскими. Знаки препинания и прочие разделители удаляются,
+// пробельные символы заменяются пробелом.
+func fold(s string) []item {
+	var items []item
+	i := 0
+	for _, r := range s {
+		for _, c := range norm.NFKD.String(norm.NFKC.String(string(r))) {
+			switch {
+			case unicode.Is(unicode.Mn, c):
+				// диакритические знаки (в т.ч. отделённые NFKD от й и ё) - ниже восстанавливается й
+			case unicode.IsLetter(c) || unicode.IsDigit(c):
+				c = unicode.ToLower(c)
+				if h, ok := homoglyphs[c]; ok {
+					c = h
+				}
+				kind := kindLetter
+				if _, ok := leet[c]; ok {
+					kind = kindLeet
+				} else if unicode.IsDigit(c) {
+					kind = kindDigit
+				}
+				items = append(items, item{r: c, kind: kind, start: i, end: i + 1})
+			case unicode.IsSpace(c):
+				items = append(items, item{r: ' ', kind: kindSpace, start: i, end: i + 1})
+			default:
+				if _, ok := leet[c]; ok {
+					items = append(items, item{r: c, kind: kindLeet, start: i, end: i + 1})
+				}
+			}
+		}
+		// й отличается от и только диакритикой, но это разные буквы.
+		if r == 'й' || r == 'Й' {
+			items[len(items)-1].r = 'й'
+		}
+		i++
+	}
+	return items
+}
+
+// trimSigns удаляет знаки в конце слова ("qwerty!", "qwerty!!!"): там это знаки препинания,
+// а не замена букв. Цифры сохраняются.
+func trimSigns(items []item) []item {
+	out := items[:0:0]
+	for i := len(items) - 1; i >= 0; i-- {
+		it := items[i]
+		atEnd := len(out) == 0 || out[len(out)-1].kind == kindSpace
+		if it.kind == kindLeet && !unicode.IsDigit(it.r) && atEnd {
+			continue
+		}
+		out = append(out, it)
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
+// resolveLeet заменяет цифры и знаки буквами, если рядом с ними есть буква
+// (с учётом соседних заменяемых символов: "3@" в "qw3@rty").
+// Остальные цифры сохраняются, знаки удаляются.
+func resolveLeet(items []item) []item {
+	letterNear := func(i int) bool {
+		for j := i - 1; j >= 0 && items[j].kind != kindSpace; j-- {
+			if items[j].kind == kindLetter {
+				return true
+			}
+		}
+		for j := i + 1; j < len(items) && items[j].kind != kindSpace; j++ {
+			if items[j].kind == kindLetter {
+				return true
+			}
+		}
+		return false
+	}
+	out := items[:0:0]
+	for i, it := range items {
+		if it.kind == kindLeet {
+			switch {
+			case letterNear(i):
+				it.r = leet[it.r]
+				it.kind = kindLetter
+			case unicode.IsDigit(it.r):
+				it.kind = kindDigit
+			default:
+				continue
+			}
+		}
+		out = append(out, it)
+	}
+	return out
+}
+
+// joinSpelledOut удаляет пробелы между одиночными буквами ("q w e r t y" -> "qwerty")
+// и лишние пробелы в начале, в конце и между словами.
+func joinSpelledOut(items []item) []item {
+	// Длина слова, в которое входит символ i.
+	wordLen := func(i int) int {
+		s, e := i, i
+		for s > 0 && items[s-1].kind != kindSpace {
+			s--
+		}
+		for e < len(items) && items[e].kind != kindSpace {
+			e++
+		}
+		return e - s
+	}
+	out := items[:0:0]
+	for i, it := range items {
+		if it.kind == kindSpace {
+			if len(out) == 0 || out[len(out)-1].kind == kindSpace {
+				continue
+			}
+			// Ближайшие непробельные символы до и после пробела.
+			p := i - 1
+			for items[p].kind == kindSpace {
+				p--
+			}
+			j := i + 1
+			for j < len(items) && items[j].kind == kindSpace {
+				j++
+			}
+			if j == len(items) {
+				continue
+			}
+			if wordLen(p) == 1 && wordLen(j) == 1 {
+				continue
+			}
+		}
+		out = append(out, it)
+	}
+	return out
+}
+
+// collapse заменяет повторы одного символа одним символом, расширяя его исходный фрагмент.
+func collapse(items []item) []item {
+	out := items[:0:0]
+	for _, it := range items {
+		if n := len(out); n > 0 && out[n-1].r == it.r {
+			out[n-1].end = it.end
+			continue
+		}
+		out = append(out, it)
+	}
+	return out
+}
@@ -0,0 +1,102 @@
+package normalize
+
+import (
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		// способы обхода проверки
+		{name: "точки между буквами", text: "Q.w.e.r.t.y", want: "qwerty"},
+		{name: "пробелы между буквами", text: "q w e r t y", want: "qwerty"},
+		{name: "цифра вместо буквы", text: "qw3rty", want: "qwerty"},
+		{name: "цифра и знак вместо букв", text: "qw3@rty", want: "qwearty"},
+		{name: "цифры в нескольких словах", text: "h3ll0 w0rld", want: "helo world"},
+		{name: "знак в начале слова", text: "$ale", want: "sale"},
+		{name: "кириллическая е в латинском слове", text: "qwеrty", want: "qwerty"},
+		{name: "латинские буквы в кириллическом слове", text: "дурaк", want: "дypak"},
+		{name: "кириллическое слово из похожих букв", text: "сор", want: "cop"},
+		{name: "греческие буквы", text: "qωεrτy", want: "qwerty"},
+		{name: "полноширинные символы", text: "ｑｗｅｒｔｙ", want: "qwerty"},
+		{name: "математические символы", text: "𝐪𝐰𝐞𝐫𝐭𝐲", want: "qwerty"},
+		{name: "лигатура", text: "ﬁle", want: "file"},
+		{name: "регистр и диакритика", text: "CAFÉ", want: "cafe"},
+		{name: "повтор букв", text: "qwwweerty", want: "qwerty"},
+		{name: "ё и е", text: "ёлка", want: "eлka"},
+		// й и и - разные буквы
+		{name: "й", text: "мой", want: "moй"},
+		{name: "и", text: "мои", want: "moи"},
+		{name: "заглавная Й", text: "МОЙ", want: "moй"},
+		// текст, который не должен искажаться
+		{name: "число", text: "2024", want: "2024"},
+		{name: "число между словами", text: "в 2024 году", want: "b 2024 гoдy"},
+		{name: "знак препинания в конце слова", text: "qwerty!", want: "qwerty"},
+		{name: "несколько знаков в конце слова", text: "qwerty!!! да", want: "qwerty дa"},
+		{name: "точки и знак в конце", text: "q.w.e.r.t.y!", want: "qwerty"},
+		{name: "лишние пробелы", text: "  qwerty \t\n да  ", want: "qwerty дa"},
+		{name: "одиночные буквы рядом со словами", text: "a b cd e f", want: "ab cd ef"},
+		{name: "пустой текст", text: "", want: ""},
+		{name: "только знаки", text: "?!.", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize(tt.text).String(); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTerm(t *testing.T) {
+	// Слово словаря и его варианты в тексте нормализуются одинаково.
+	for _, s := range []string{"Q.w.e.r.t.y", "q w e r t y", "qw3rty", "qwеrty", "ｑｗｅｒｔｙ", "qwwweerty", "qwerty!"} {
+		if got, want := Normalize(s).String(), Term("qwerty"); got != want {
+			t.Errorf("Normalize(%q) = %q, want Term(\"qwerty\") = %q", s, got, want)
+		}
+	}
+	if Term("мой") == Term("мои") {
+		t.Errorf("Term(\"мой\") = Term(\"мои\") = %q, want разные", Term("мой"))
+	}
+}
+
+func TestText_Span(t *testing.T) {
+	tests := []struct {
+		name       string
+		text       string
+		start, end int // фрагмент нормализованного текста
+		wantStart  int // позиции исходного текста в символах
+		wantEnd    int
+	}{
+		{name: "весь текст с точками", text: "Q.w.e.r.t.y", start: 0, end: 6, wantStart: 0, wantEnd: 11},
+		{name: "буква между точками", text: "Q.w.e.r.t.y", start: 1, end: 2, wantStart: 2, wantEnd: 3},
+		{name: "повтор букв", text: "qwwweerty", start: 1, end: 3, wantStart: 1, wantEnd: 6},
+		{name: "знак в конце не входит", text: "qwerty!", start: 0, end: 6, wantStart: 0, wantEnd: 6},
+		{name: "второе слово", text: "ну qw3rty!", start: 3, end: 9, wantStart: 3, wantEnd: 9},
+		{name: "лишние пробелы", text: "  qwerty  ", start: 0, end: 6, wantStart: 2, wantEnd: 8},
+		{name: "символы вне BMP", text: "да 𝐪𝐰𝐞𝐫𝐭𝐲", start: 3, end: 9, wantStart: 3, wantEnd: 9},
+		{name: "лигатура", text: "ﬁle", start: 0, end: 2, wantStart: 0, wantEnd: 1},
+		{name: "пустой фрагмент", text: "qwerty", start: 2, end: 2, wantStart: 0, wantEnd: 0},
+		{name: "за концом текста", text: "qwerty", start: 0, end: 7, wantStart: 0, wantEnd: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := Normalize(tt.text)
+			start, end := n.Span(tt.start, tt.end)
+			if start != tt.wantStart || end != tt.wantEnd {
+				t.Errorf("Span(%d, %d) для %q (%q) = %d, %d, want %d, %d",
+					tt.start, tt.end, tt.text, n.String(), start, end, tt.wantStart, tt.wantEnd)
+			}
+			// Исходный фрагмент непуст и нормализуется в тот же фрагмент.
+			if tt.wantEnd > tt.wantStart {
+				orig := string([]rune(tt.text)[start:end])
+				if got, want := Term(orig), string(n.Runes[tt.start:tt.end]); got != want {
+					t.Errorf("Term(%q) = %q, want %q", orig, got, want)
+				}
+			}
+		})
+	}
+}
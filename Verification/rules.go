@@ -0,0 +1,190 @@
+package main
+
+import (
+	"APIGateway/Verification/config"
+	"APIGateway/Verification/dictionary"
+	"APIGateway/Verification/duplicate"
+	"APIGateway/Verification/normalize"
+	"APIGateway/Verification/spam"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Правило проверки комментария. Правила выполняются в порядке, заданном
+// политикой проверки (config.Pipeline).
+type Rule interface {
+	Check(in *input) ruleResult
+}
+
+// Правило, которому нужно знать итог проверки (например, чтобы запомнить принятый комментарий).
+type recorder interface {
+	Record(in *input, accepted bool)
+}
+
+// Проверяемый комментарий и общие для всех правил данные.
+type input struct {
+	comment   Comment
+	author    string         // IP-адрес автора; пустой - неизвестен
+	policy    *config.Policy // политика проверки
+	forceMask bool           // маскировать все найденные слова
+	tm        *termMatcher   // правила словаря
+	text      normalize.Text // нормализованный текст
+	original  []rune         // исходный текст
+	checkID   string         // идентификатор проверки для подтверждения сохранения комментария
+	now       time.Time
+}
+
+// Результат правила.
+type ruleResult struct {
+	matches []VerdictMatch // найденные слова словаря
+	spam    []spam.Hit     // найденные признаки спама
+	score   int            // оценка
+	reject  string         // код причины безусловного отклонения; пустой - правило не отклоняет комментарий
+	reason  string         // код причины, если оценка правила привела к превышению порога
+}
+
+// newRules создаёт правила по конфигурации.
+func newRules(cfg *config.Config, duplicates *duplicate.Store) map[string]Rule {
+	rules := map[string]Rule{
+		config.RuleBannedWords: wordsRule{},
+		config.RuleRegex:       regexRule{},
+		config.RuleSpam:        spamRule{checker: spam.New(cfg.Spam), reason: ReasonSpam},
+		config.RuleLength:      spamRule{checker: spam.NewLength(cfg.Spam), reason: ReasonTooLong},
+	}
+	if duplicates != nil {
+		rules[config.RuleDuplicate] = duplicateRule{store: duplicates}
+	}
+	return rules
+}
+
+// Слова словаря (типы word и prefix).
+type wordsRule struct{}
+
+func (wordsRule) Check(in *input) ruleResult {
+	var res ruleResult
+	runes := in.text.Runes
+	// Слово целиком: вхождение от границы до границы слова нормализованного текста.
+	for _, m := range in.tm.words.FindAll(runes) {
+		if wordStart(runes, m.Start) && wordEnd(runes, m.End) {
+			start, end := in.text.Span(m.Start, m.End)
+			res.add(in, in.tm.wordRules[m.Pattern], start, end)
+		}
+	}
+	// Основа: вхождение от границы слова, фрагмент продолжается до конца слова.
+	for _, m := range in.tm.prefixes.FindAll(runes) {
+		if wordStart(runes, m.Start) {
+			end := m.End
+			for end < len(runes) && !wordEnd(runes, end) {
+				end++
+			}
+			start, end := in.text.Span(m.Start, end)
+			res.add(in, in.tm.prefixRules[m.Pattern], start, end)
+		}
+	}
+	return res
+}
+
+// Регулярные выражения словаря. Применяются к исходному тексту.
+type regexRule struct{}
+
+func (regexRule) Check(in *input) ruleResult {
+	var res ruleResult
+	comment := in.comment.Comment
+	for _, r := range in.tm.regexps {
+		for _, loc := range r.re.FindAllStringIndex(comment, -1) {
+			start := utf8.RuneCountInString(comment[:loc[0]])
+			end := start + utf8.RuneCountInString(comment[loc[0]:loc[1]])
+			res.add(in, r.term, start, end)
+		}
+	}
+	return res
+}
+
+// add добавляет найденное слово словаря, если оно не входит в исключения.
+// Комментарий отклоняется, если для категории слова действие reject.
+func (res *ruleResult) add(in *input, t dictionary.Term, start, end int) {
+	if start >= end || in.tm.allowed(in.original, start, end) {
+		return
+	}
+	action := in.policy.Action(t.Category)
+	if in.forceMask {
+		action = config.ActionMask
+	}
+	res.matches = append(res.matches, VerdictMatch{
+		Term:     t.Term,
+		Category: t.Category,
+		Severity: t.Severity,
+		Start:    start,
+		End:      end,
+		Text:     string(in.original[start:end]),
+		Action:   action,
+	})
+	res.score += t.Severity
+	res.reason = ReasonBannedWords
+	if action == config.ActionReject {
+		res.reject = ReasonBannedWords
+	}
+}
+
+// Признаки спама.
+type spamRule struct {
+	checker *spam.Checker
+	reason  string
+}
+
+func (r spamRule) Check(in *input) ruleResult {
+	res := ruleResult{spam: r.checker.Check(in.comment.Comment), reason: r.reason}
+	for _, h := range res.spam {
+		res.score += h.Score
+	}
+	return res
+}
+
+// Повторы и флуд.
+type duplicateRule struct {
+	store *duplicate.Store
+}
+
+func (r duplicateRule) Check(in *input) ruleResult {
+	switch r.store.CheckAndRecord(in.checkID, in.comment.NewsID, in.author, in.text.String(), in.now) {
+	case duplicate.Flood:
+		return ruleResult{reject: ReasonFlood}
+	case duplicate.Duplicate:
+		return ruleResult{reject: ReasonDuplicate}
+	}
+	return ruleResult{}
+}
+
+// Record завершает проверку отклонённого комментария. Принятый комментарий ожидает
+// подтверждения сохранения от APIGateway (метод /verification/confirm).
+func (r duplicateRule) Record(in *input, accepted bool) {
+	if !accepted {
+		r.store.Confirm(in.checkID, false)
+	}
+}
+
+// wordStart сообщает, что позиция i нормализованного текста - начало слова.
+func wordStart(text []rune, i int) bool {
+	return i == 0 || text[i-1] == ' '
+}
+
+// wordEnd сообщает, что позиция i нормализованного текста - конец слова.
+func wordEnd(text []rune, i int) bool {
+	return i == len(text) || text[i] == ' '
+}
+
+// allowed сообщает, что фрагмент исходного текста [start, end), дополненный до границ слов,
+// совпадает со словом-исключением.
+func (tm *termMatcher) allowed(original []rune, start, end int) bool {
+	if len(tm.allow) == 0 {
+		return false
+	}
+	for start > 0 && !unicode.IsSpace(original[start-1]) {
+		start--
+	}
+	for end < len(original) && !unicode.IsSpace(original[end]) {
+		end++
+	}
+	return tm.allow[normalize.Term(string(original[start:end]))]
+}
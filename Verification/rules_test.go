@@ -0,0 +1,211 @@
+package main
+
+import (
+	"APIGateway/Verification/config"
+	"APIGateway/Verification/dictionary"
+	"APIGateway/Verification/normalize"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// Словарь для проверки правил: по правилу каждого типа на русском и английском.
+const testDictionary = `
+гад;insult;2;word
+ass;insult;2;word
+дурак;insult;3;prefix
+idiot;insult;3;prefix
+скот;insult;3;prefix
+cock;profanity;5;prefix
+скотоводство;;;allow
+cockpit;;;allow
+гадин(а|ы);insult;4;regex
+d[uy]mb\s?ass;insult;4;regex
+`
+
+// testInput подготавливает комментарий text для проверки правилами словаря testDictionary.
+func testInput(t *testing.T, text string, policy *config.Policy) *input {
+	t.Helper()
+	terms, err := dictionary.Parse([]byte(testDictionary))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &input{
+		comment:  Comment{NewsID: 1, Comment: text},
+		policy:   policy,
+		tm:       newTermMatcher(1, terms),
+		text:     normalize.Normalize(text),
+		original: []rune(text),
+	}
+}
+
+// matched возвращает найденные фрагменты комментария.
+func matched(res ruleResult) []string {
+	var s []string
+	for _, m := range res.matches {
+		s = append(s, m.Text)
+	}
+	return s
+}
+
+func TestWordsRule(t *testing.T) {
+	policy := &config.Policy{Name: config.DefaultPolicy, DefaultAction: config.ActionReject}
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		// word: слово целиком
+		{name: "word RU", text: "ты гад!", want: []string{"гад"}},
+		{name: "word EN", text: "you ass", want: []string{"ass"}},
+		{name: "word RU с обходом", text: "ты г.а.д", want: []string{"г.а.д"}},
+		{name: "word EN с обходом", text: "you a$s", want: []string{"a$s"}},
+		{name: "word RU внутри безобидного слова", text: "загадка и гадание", want: nil},
+		{name: "word EN внутри безобидного слова", text: "a classic assignment", want: nil},
+		// prefix: основа и словоформы
+		{name: "prefix RU начальная форма", text: "сам дурак", want: []string{"дурак"}},
+		{name: "prefix RU словоформа", text: "не будь дураками", want: []string{"дураками"}},
+		{name: "prefix EN начальная форма", text: "what an idiot", want: []string{"idiot"}},
+		{name: "prefix EN словоформа", text: "Idiots everywhere", want: []string{"Idiots"}},
+		{name: "prefix RU не с начала слова", text: "придурок", want: nil},
+		{name: "prefix EN не с начала слова", text: "peacock", want: nil},
+		// allow: исключение подавляет пересекающееся правило
+		{name: "allow RU", text: "развитое скотоводство", want: nil},
+		{name: "allow EN", text: "in the cockpit", want: nil},
+		{name: "allow не подавляет другие слова", text: "cockpit cocks", want: []string{"cocks"}},
+		{name: "allow RU не подавляет другие слова", text: "скотоводство и скотина", want: []string{"скотина"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := wordsRule{}.Check(testInput(t, tt.text, policy))
+			if got := matched(res); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("wordsRule.Check(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegexRule(t *testing.T) {
+	policy := &config.Policy{Name: config.DefaultPolicy, DefaultAction: config.ActionReject}
+	tests := []struct {
+		name      string
+		text      string
+		want      []string
+		wantStart int // позиция первого найденного фрагмента в символах
+	}{
+		{name: "RU", text: "эта гадина", want: []string{"гадина"}, wantStart: 4},
+		{name: "RU без учёта регистра", text: "Эта ГАДИНА", want: []string{"ГАДИНА"}, wantStart: 4},
+		{name: "EN", text: "so dumb ass", want: []string{"dumb ass"}, wantStart: 3},
+		{name: "EN без учёта регистра", text: "so DumbAss", want: []string{"DumbAss"}, wantStart: 3},
+		{name: "несколько вхождений", text: "гадины, dymbass", want: []string{"гадины", "dymbass"}, wantStart: 0},
+		{name: "нет вхождений", text: "загадка", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := regexRule{}.Check(testInput(t, tt.text, policy))
+			got := matched(res)
+			// порядок вхождений - порядок выражений в словаре
+			if !reflect.DeepEqual(sorted(got), sorted(tt.want)) {
+				t.Fatalf("regexRule.Check(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+			if len(got) > 0 {
+				start := res.matches[0].Start
+				for _, m := range res.matches {
+					start = min(start, m.Start)
+				}
+				if start != tt.wantStart {
+					t.Errorf("Start = %d, want %d", start, tt.wantStart)
+				}
+			}
+		})
+	}
+}
+
+// sorted возвращает копию строк в порядке возрастания.
+func sorted(s []string) []string {
+	c := append([]string(nil), s...)
+	sort.Strings(c)
+	return c
+}
+
+func TestRuleResult_add(t *testing.T) {
+	term := dictionary.Term{Term: "дурак", Category: "insult", Severity: 3, Type: dictionary.TypePrefix}
+	tests := []struct {
+		name       string
+		policy     *config.Policy
+		forceMask  bool
+		text       string
+		start, end int
+		wantAction string // пустое - слово не добавляется
+		wantReject string
+	}{
+		{
+			name:       "действие по умолчанию reject",
+			policy:     &config.Policy{DefaultAction: config.ActionReject},
+			text:       "сам дурак",
+			start:      4,
+			end:        9,
+			wantAction: config.ActionReject,
+			wantReject: ReasonBannedWords,
+		},
+		{
+			name:       "действие категории mask",
+			policy:     &config.Policy{DefaultAction: config.ActionReject, Actions: map[string]string{"insult": config.ActionMask}},
+			text:       "сам дурак",
+			start:      4,
+			end:        9,
+			wantAction: config.ActionMask,
+		},
+		{
+			name:       "режим mask",
+			policy:     &config.Policy{DefaultAction: config.ActionReject},
+			forceMask:  true,
+			text:       "сам дурак",
+			start:      4,
+			end:        9,
+			wantAction: config.ActionMask,
+		},
+		{
+			name:   "пустой фрагмент",
+			policy: &config.Policy{DefaultAction: config.ActionReject},
+			text:   "сам дурак",
+			start:  4,
+			end:    4,
+		},
+		{
+			name:   "слово-исключение",
+			policy: &config.Policy{DefaultAction: config.ActionReject},
+			text:   "развитое скотоводство",
+			start:  9,
+			end:    13,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := testInput(t, tt.text, tt.policy)
+			in.forceMask = tt.forceMask
+			var res ruleResult
+			res.add(in, term, tt.start, tt.end)
+			if tt.wantAction == "" {
+				if len(res.matches) != 0 || res.score != 0 || res.reject != "" {
+					t.Fatalf("add() = %+v, want пустой результат", res)
+				}
+				return
+			}
+			if len(res.matches) != 1 {
+				t.Fatalf("add() добавил %d слов, want 1", len(res.matches))
+			}
+			m := res.matches[0]
+			if m.Action != tt.wantAction {
+				t.Errorf("Action = %q, want %q", m.Action, tt.wantAction)
+			}
+			if want := string([]rune(tt.text)[tt.start:tt.end]); m.Text != want {
+				t.Errorf("Text = %q, want %q", m.Text, want)
+			}
+			if res.score != term.Severity || res.reason != ReasonBannedWords || res.reject != tt.wantReject {
+				t.Errorf("score, reason, reject = %d, %q, %q, want %d, %q, %q",
+					res.score, res.reason, res.reject, term.Severity, ReasonBannedWords, tt.wantReject)
+			}
+		})
+	}
+}
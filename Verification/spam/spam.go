@@ -0,0 +1,285 @@
+// Пакет для проверки комментариев на спам.
+//
+// Проверки независимы друг от друга: каждая реализует интерфейс Check и возвращает
+// найденные признаки спама с баллами. Набор проверок строится по конфигурации.
+package spam
+
+import (
+	"APIGateway/Verification/config"
+	"APIGateway/Verification/matcher"
+	"APIGateway/Verification/normalize"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Названия проверок.
+const (
+	CheckURLs          = "urls"
+	CheckBlockedDomain = "blocked_domain"
+	CheckCaps          = "caps"
+	CheckRepeatedChars = "repeated_chars"
+	CheckPhrase        = "phrase"
+	CheckMaxLength     = "max_length"
+)
+
+// Признак спама. Позиции - в символах (Unicode code points) текста комментария.
+type Hit struct {
+	Check string `json:"Check"` // название проверки
+	Score int    `json:"Score"` // балл
+	Start int    `json:"Start"`
+	End   int    `json:"End"`
+	Text  string `json:"Text"` // найденный фрагмент или описание нарушения
+}
+
+// Проверка комментария.
+type Check interface {
+	Check(text []rune) []Hit
+}
+
+// Набор проверок.
+type Checker struct {
+	checks []Check
+}
+
+// New строит набор проверок по конфигурации, кроме проверки длины.
+// Проверки с нулевым баллом пропускаются.
+func New(cfg config.Spam) *Checker {
+	var c Checker
+	allowed := domainSet(cfg.AllowedDomains)
+	if cfg.URLs != nil && cfg.URLs.Score > 0 {
+		c.checks = append(c.checks, urlCheck{max: cfg.URLs.Max, score: cfg.URLs.Score, allowed: allowed})
+	}
+	if cfg.BlockedDomains != nil && cfg.BlockedDomains.Score > 0 {
+		c.checks = append(c.checks, blockedDomainCheck{blocked: domainSet(cfg.BlockedDomains.Domains), allowed: allowed, score: cfg.BlockedDomains.Score})
+	}
+	if cfg.Caps != nil && cfg.Caps.Score > 0 {
+		c.checks = append(c.checks, capsCheck(*cfg.Caps))
+	}
+	if cfg.RepeatedChars != nil && cfg.RepeatedChars.Score > 0 {
+		c.checks = append(c.checks, repeatCheck(*cfg.RepeatedChars))
+	}
+	if cfg.Phrases != nil && cfg.Phrases.Score > 0 && len(cfg.Phrases.Phrases) > 0 {
+		c.checks = append(c.checks, newPhraseCheck(cfg.Phrases.Phrases, cfg.Phrases.Score))
+	}
+	return &c
+}
+
+// NewLength строит проверку длины комментария (spam.max_length). Она выполняется
+// отдельным правилом, поэтому не входит в набор New.
+func NewLength(cfg config.Spam) *Checker {
+	var c Checker
+	if cfg.MaxLength != nil && cfg.MaxLength.Score > 0 {
+		c.checks = append(c.checks, lengthCheck(*cfg.MaxLength))
+	}
+	return &c
+}
+
+// Check выполняет все проверки.
+func (c *Checker) Check(text string) []Hit {
+	runes := []rune(text)
+	var hits []Hit
+	for _, check := range c.checks {
+		hits = append(hits, check.Check(runes)...)
+	}
+	return hits
+}
+
+// Ссылки: со схемой, начинающиеся с www. и домены вида example.com.
+var urlRe = regexp.MustCompile(`(?i)\b(?:https?://[^\s<>"']+|www\.[^\s<>"']+|[a-z0-9][a-z0-9-]*(?:\.[a-z0-9-]+)*\.(?:[a-z]{2,}|рф)(?:/[^\s<>"']*)?)`)
+
+// Доменные зоны, по которым домен без схемы и www. (example.com) считается ссылкой
+// для ограничения числа ссылок. Слова через точку без пробела ("Hello.World", "mr.Smith",
+// "конец.Далее") и имена файлов ("report.txt") так не считаются ссылками.
+// Зоны, совпадающие с частыми короткими словами (in, it, is, to, me и т.п.), не включены.
+var bareDomainZones = map[string]bool{
+	"com": true, "net": true, "org": true, "info": true, "biz": true,
+	"ru": true, "su": true, "рф": true, "ua": true, "kz": true, "uz": true,
+	"de": true, "uk": true, "fr": true, "pl": true, "cn": true,
+	"io": true, "co": true, "tv": true, "cc": true, "ws": true,
+	"xyz": true, "top": true, "online": true, "site": true, "shop": true, "club": true,
+}
+
+// Ссылка в тексте.
+type link struct {
+	host       string
+	bare       bool // домен без схемы и www.
+	start, end int  // позиции в символах
+}
+
+// counted сообщает, что ссылка учитывается в ограничении числа ссылок:
+// ссылка со схемой или www. либо домен из известной доменной зоны.
+func (l link) counted() bool {
+	if !l.bare {
+		return true
+	}
+	return bareDomainZones[l.host[strings.LastIndex(l.host, ".")+1:]]
+}
+
+// findLinks находит ссылки в тексте.
+func findLinks(text []rune) []link {
+	s := string(text)
+	var links []link
+	for _, loc := range urlRe.FindAllStringIndex(s, -1) {
+		// знаки препинания после ссылки не входят в неё
+		raw := strings.TrimRight(s[loc[0]:loc[1]], ".,;:!?)")
+		start := utf8.RuneCountInString(s[:loc[0]])
+		end := start + utf8.RuneCountInString(raw)
+		bare := !strings.Contains(raw, "://") && !strings.HasPrefix(strings.ToLower(raw), "www.")
+		if !strings.Contains(raw, "://") {
+			raw = "http://" + raw
+		}
+		u, err := url.Parse(raw)
+		if err != nil || u.Hostname() == "" {
+			continue
+		}
+		links = append(links, link{
+			host:  strings.TrimPrefix(strings.ToLower(u.Hostname()), "www."),
+			bare:  bare,
+			start: start,
+			end:   end,
+		})
+	}
+	return links
+}
+
+// Набор доменов.
+type domains map[string]bool
+
+func domainSet(list []string) domains {
+	d := make(domains, len(list))
+	for _, s := range list {
+		d[strings.TrimPrefix(strings.ToLower(strings.TrimSpace(s)), "www.")] = true
+	}
+	return d
+}
+
+// contains сообщает, что хост - домен из набора или его поддомен.
+func (d domains) contains(host string) bool {
+	for {
+		if d[host] {
+			return true
+		}
+		i := strings.IndexByte(host, '.')
+		if i < 0 {
+			return false
+		}
+		host = host[i+1:]
+	}
+}
+
+// Ограничение числа ссылок (ссылки на разрешённые домены и домены без схемы
+// из неизвестных доменных зон не учитываются).
+type urlCheck struct {
+	max     int
+	score   int
+	allowed domains
+}
+
+func (c urlCheck) Check(text []rune) []Hit {
+	var links []link
+	for _, l := range findLinks(text) {
+		if l.counted() && !c.allowed.contains(l.host) {
+			links = append(links, l)
+		}
+	}
+	if len(links) <= c.max {
+		return nil
+	}
+	first, last := links[0], links[len(links)-1]
+	return []Hit{{Check: CheckURLs, Score: c.score, Start: first.start, End: last.end,
+		Text: fmt.Sprintf("ссылок: %d, допустимо: %d", len(links), c.max)}}
+}
+
+// Ссылки на запрещённые домены.
+type blockedDomainCheck struct {
+	blocked domains
+	allowed domains
+	score   int
+}
+
+func (c blockedDomainCheck) Check(text []rune) []Hit {
+	var hits []Hit
+	for _, l := range findLinks(text) {
+		if c.blocked.contains(l.host) && !c.allowed.contains(l.host) {
+			hits = append(hits, Hit{Check: CheckBlockedDomain, Score: c.score, Start: l.start, End: l.end, Text: string(text[l.start:l.end])})
+		}
+	}
+	return hits
+}
+
+// Доля заглавных букв.
+type capsCheck config.CapsLimit
+
+func (c capsCheck) Check(text []rune) []Hit {
+	var letters, upper int
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	if letters == 0 || letters < c.MinLetters || float64(upper)/float64(letters) < c.Ratio {
+		return nil
+	}
+	return []Hit{{Check: CheckCaps, Score: c.Score, Start: 0, End: len(text),
+		Text: fmt.Sprintf("заглавных букв: %d из %d", upper, letters)}}
+}
+
+// Повторы одного символа.
+type repeatCheck config.RepeatLimit
+
+func (c repeatCheck) Check(text []rune) []Hit {
+	var hits []Hit
+	for i := 0; i < len(text); {
+		j := i + 1
+		for j < len(text) && unicode.ToLower(text[j]) == unicode.ToLower(text[i]) {
+			j++
+		}
+		if j-i > c.Max && !unicode.IsSpace(text[i]) {
+			hits = append(hits, Hit{Check: CheckRepeatedChars, Score: c.Score, Start: i, End: j, Text: string(text[i:j])})
+		}
+		i = j
+	}
+	return hits
+}
+
+// Известные спам-фразы.
+type phraseCheck struct {
+	m     *matcher.Matcher
+	score int
+}
+
+func newPhraseCheck(phrases []string, score int) phraseCheck {
+	patterns := make([]string, len(phrases))
+	for i, p := range phrases {
+		patterns[i] = normalize.Term(p)
+	}
+	return phraseCheck{m: matcher.New(patterns), score: score}
+}
+
+func (c phraseCheck) Check(text []rune) []Hit {
+	var hits []Hit
+	norm := normalize.Normalize(string(text))
+	for _, m := range c.m.FindAll(norm.Runes) {
+		start, end := norm.Span(m.Start, m.End)
+		hits = append(hits, Hit{Check: CheckPhrase, Score: c.score, Start: start, End: end, Text: string(text[start:end])})
+	}
+	return hits
+}
+
+// Длина комментария.
+type lengthCheck config.LengthLimit
+
+func (c lengthCheck) Check(text []rune) []Hit {
+	if len(text) <= c.Max {
+		return nil
+	}
+	return []Hit{{Check: CheckMaxLength, Score: c.Score, Start: c.Max, End: len(text),
+		Text: fmt.Sprintf("длина: %d, допустимо: %d", len(text), c.Max)}}
+}
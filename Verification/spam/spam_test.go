@@ -0,0 +1,82 @@
+package spam
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_findLinks(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []link
+	}{
+		{
+			name: "со схемой",
+			text: "см. https://Example.com/a?b=1.",
+			want: []link{{host: "example.com", start: 4, end: 29}},
+		},
+		{
+			name: "www.",
+			text: "www.example.org, и всё",
+			want: []link{{host: "example.org", start: 0, end: 15}},
+		},
+		{
+			name: "домен без схемы",
+			text: "пишите на promo.рф или example.xyz/path",
+			want: []link{{host: "promo.рф", bare: true, start: 10, end: 18}, {host: "example.xyz", bare: true, start: 23, end: 39}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findLinks([]rune(tt.text)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findLinks(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_urlCheck(t *testing.T) {
+	c := urlCheck{max: 0, score: 3, allowed: domainSet([]string{"habr.com"})}
+	tests := []struct {
+		name string
+		text string
+		want bool // найден признак спама
+	}{
+		// ссылки
+		{name: "ссылка со схемой", text: "заходите http://spam.example", want: true},
+		{name: "www. в неизвестной зоне", text: "заходите www.spam.example", want: true},
+		{name: "домен без схемы .com", text: "заходите на spam.com", want: true},
+		{name: "домен без схемы .ru в верхнем регистре", text: "ЗАХОДИТЕ НА SPAM.RU", want: true},
+		{name: "домен без схемы .рф", text: "заходите на promo.рф", want: true},
+		{name: "поддомен без схемы", text: "заходите на promo.spam.xyz/win", want: true},
+		{name: "разрешённый домен", text: "статья на https://habr.com/ru/articles/1", want: false},
+		// не ссылки
+		{name: "слова через точку", text: "Hello.World", want: false},
+		{name: "сокращение без пробела", text: "Спасибо, mr.Smith!", want: false},
+		{name: "нет пробела после точки", text: "That is the end.Next time", want: false},
+		{name: "нет пробела после точки, русский", text: "Вот и всё.Дальше сами", want: false},
+		{name: "имя файла", text: "откройте файл report.txt или photo.jpeg", want: false},
+		{name: "номер версии", text: "вышла версия 1.2.3", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := len(c.Check([]rune(tt.text))) > 0; got != tt.want {
+				t.Errorf("urlCheck.Check(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_blockedDomainCheck(t *testing.T) {
+	c := blockedDomainCheck{blocked: domainSet([]string{"spam.example"}), allowed: domainSet(nil), score: 5}
+	// Домен из списка без схемы находится в любой доменной зоне.
+	for _, text := range []string{"spam.example", "http://spam.example/x", "www.promo.spam.example"} {
+		if hits := c.Check([]rune(text)); len(hits) != 1 {
+			t.Errorf("blockedDomainCheck.Check(%q) = %d признаков, want 1", text, len(hits))
+		}
+	}
+	if hits := c.Check([]rune("Hello.World")); len(hits) != 0 {
+		t.Errorf("blockedDomainCheck.Check(%q) = %d признаков, want 0", "Hello.World", len(hits))
+	}
+}
@@ -0,0 +1,77 @@
+// Пакет для выделения основы слова (упрощённый стеммер для русского и английского языков).
+//
+// От слова отсекается самое длинное из известных окончаний и суффиксов словоизменения,
+// если после этого остаётся основа не короче MinLen символов. Стеммер не претендует
+// на лингвистическую точность: он нужен, чтобы слово словаря в начальной форме
+// ("дурак", "idiot") находило словоформы ("дураками", "idiots").
+package stem
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Минимальная длина основы в символах.
+const MinLen = 3
+
+// Окончания русских существительных, прилагательных и глаголов.
+var ruEndings = []string{
+	"иями", "ями", "ами", "ией", "иях", "ях", "ах", "ов", "ев", "ей", "ий", "ый", "ой", "ая", "яя",
+	"ое", "ее", "ые", "ие", "ого", "его", "ому", "ему", "ым", "им", "ом", "ем", "ам", "ям", "ую", "юю",
+	"ться", "тся", "ешь", "ете", "ите", "ишь", "ать", "ять", "ить", "еть", "уть", "ют", "ут", "ат", "ят", "ет", "ит",
+	"ал", "ял", "ил", "ел", "ала", "яла", "ила", "ела", "али", "яли", "или", "ели",
+	"а", "я", "о", "е", "ы", "и", "у", "ю", "ь",
+}
+
+// Окончания английских слов.
+var enEndings = []string{
+	"ingly", "edly", "ings", "ness", "ing", "ies", "ied", "ers", "est", "ed", "es", "er", "ly", "s", "y",
+}
+
+// Stem возвращает основу слова. Слово приводится к нижнему регистру,
+// ё заменяется на е. Для слов не из кириллицы и латиницы слово возвращается без изменений.
+func Stem(word string) string {
+	word = strings.ReplaceAll(strings.ToLower(word), "ё", "е")
+	endings := enEndings
+	if isCyrillic(word) {
+		endings = ruEndings
+	} else if !isLatin(word) {
+		return word
+	}
+	best := ""
+	for _, e := range endings {
+		// "ss" - не окончание множественного числа: class, boss
+		if e == "s" && strings.HasSuffix(word, "ss") {
+			continue
+		}
+		if len(e) > len(best) && strings.HasSuffix(word, e) &&
+			utf8.RuneCountInString(word)-utf8.RuneCountInString(e) >= MinLen {
+			best = e
+		}
+	}
+	return strings.TrimSuffix(word, best)
+}
+
+func isCyrillic(word string) bool {
+	return hasOnly(word, unicode.Cyrillic)
+}
+
+func isLatin(word string) bool {
+	return hasOnly(word, unicode.Latin)
+}
+
+// hasOnly сообщает, что все буквы слова принадлежат алфавиту.
+func hasOnly(word string, script *unicode.RangeTable) bool {
+	letters := 0
+	for _, r := range word {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		if !unicode.Is(script, r) {
+			return false
+		}
+		letters++
+	}
+	return letters > 0
+}
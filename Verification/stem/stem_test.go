@@ -0,0 +1,44 @@
+package stem
+
+import "testing"
+
+func TestStem(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		// русский
+		{word: "дурак", want: "дурак"},
+		{word: "дурака", want: "дурак"},
+		{word: "дураки", want: "дурак"},
+		{word: "дураками", want: "дурак"},
+		{word: "ДУРАКИ", want: "дурак"},
+		{word: "ёлки", want: "елк"},
+		{word: "говорить", want: "говор"},
+		{word: "сказал", want: "сказ"},
+		// основа не короче MinLen
+		{word: "коты", want: "кот"},
+		{word: "кот", want: "кот"},
+		{word: "ты", want: "ты"},
+		// английский
+		{word: "idiot", want: "idiot"},
+		{word: "idiots", want: "idiot"},
+		{word: "Idiots", want: "idiot"},
+		{word: "cats", want: "cat"},
+		{word: "moved", want: "mov"},
+		{word: "flies", want: "fli"},
+		{word: "running", want: "runn"},
+		// "ss" - не окончание множественного числа
+		{word: "class", want: "class"},
+		{word: "boss", want: "boss"},
+		// слова из разных алфавитов и числа не изменяются
+		{word: "qwеrty", want: "qwеrty"},
+		{word: "2024", want: "2024"},
+		{word: "", want: ""},
+	}
+	for _, tt := range tests {
+		if got := Stem(tt.word); got != tt.want {
+			t.Errorf("Stem(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
@@ -1,13 +1,26 @@
 package main
 
 import (
+	"APIGateway/Verification/audit"
+	"APIGateway/Verification/config"
+	"APIGateway/Verification/dictionary"
+	"APIGateway/Verification/duplicate"
+	"APIGateway/Verification/matcher"
+	"APIGateway/Verification/normalize"
+	"APIGateway/Verification/stem"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
 )
@@ -21,15 +34,170 @@ type Comment struct {
 }
 
 var port = os.Getenv("API_PORT")
-var newComment Comment
 
-// Список запрещённых слов.
-var badWord = [3]string{"qwerty", "йцукен", "zxvbnm"}
+// Файл словаря запрещённых слов.
+var dictionaryPath = envOr("DICTIONARY_PATH", "./dictionary.txt")
+
+// Файл конфигурации: действия для категорий запрещённых слов.
+var configPath = envOr("CONFIG_PATH", "./config.json")
+
+// Файл теневого словаря: проверяется вместе с основным, не влияя на решение.
+var shadowDictionaryPath = envOr("SHADOW_DICTIONARY_PATH", "./dictionary.shadow.txt")
+
+// Журнал решений проверки и секрет для хэширования адресов авторов в нём.
+// Размер файла журнала (в мегабайтах, 0 - без ротации) и число хранимых прежних файлов.
+var (
+	auditPath     = envOr("AUDIT_PATH", "./audit.jsonl")
+	auditSecret   = os.Getenv("AUDIT_SECRET")
+	auditMaxSize  = envOr("AUDIT_MAX_SIZE_MB", "100")
+	auditMaxFiles = envOr("AUDIT_MAX_FILES", "5")
+)
+
+// Период проверки изменения файла словаря.
+const dictionaryWatchPeriod = 5 * time.Second
+
+// Словарь запрещённых слов.
+var dict *dictionary.Dictionary
+
+// Теневой словарь. Результат проверки по нему только записывается в журнал:
+// так новую версию словаря можно опробовать на реальных комментариях.
+var shadowDict *dictionary.Dictionary
+
+// Журнал решений проверки.
+var auditLog *audit.Log
+
+// Конфигурация сервиса.
+var cfg *config.Config
+
+// Отпечатки недавних комментариев для проверки повторов и флуда; nil - проверка отключена.
+var duplicates *duplicate.Store
+
+// Правила проверки по названиям.
+var rules map[string]Rule
+
+// Правила словаря, подготовленные для поиска: автоматы для слов и основ,
+// скомпилированные регулярные выражения и исключения. Строятся для версии словаря.
+type termMatcher struct {
+	version     int64
+	wordRules   []dictionary.Term // правила типа word в порядке слов автомата words
+	words       *matcher.Matcher
+	prefixRules []dictionary.Term // правила типа prefix в порядке основ автомата prefixes
+	prefixes    *matcher.Matcher
+	regexps     []termRegexp
+	allow       map[string]bool // нормализованные слова-исключения
+}
+
+// Правило типа regex.
+type termRegexp struct {
+	term dictionary.Term
+	re   *regexp.Regexp
+}
+
+// Правила словаря, перестраиваемые при изменении словаря.
+type matcherCache struct {
+	mu      sync.Mutex
+	dict    *dictionary.Dictionary
+	current *termMatcher
+}
+
+// Правила основного и теневого словарей.
+var activeMatcher, shadowMatcher matcherCache
 
 func main() {
 
+	// Создаём канал для агрегации ошибок.
+	chErrs := make(chan error)
+
+	// Обработка потока ошибок.
+	go func() {
+		for err := range chErrs {
+			log.Println("ERROR: ", err)
+		}
+	}()
+
+	var err error
+	cfg, err = config.Load(configPath)
+	if err != nil {
+		log.Fatalf("ошибка чтения конфигурации:  %v", err)
+	}
+	if d := cfg.Duplicates; d != nil {
+		window := time.Duration(d.Window) * time.Second
+		duplicates = duplicate.New(duplicate.Options{
+			Window:      window,
+			MaxDistance: d.MaxDistance,
+			MinLength:   d.MinLength,
+			FloodLimit:  d.FloodLimit,
+		})
+		go func() {
+			for range time.Tick(window) {
+				duplicates.Cleanup(time.Now())
+			}
+		}()
+	}
+	rules = newRules(cfg, duplicates)
+
+	dict, err = dictionary.Load(dictionaryPath)
+	if err != nil {
+		log.Fatalf("ошибка чтения словаря запрещённых слов:  %v", err)
+	}
+	activeMatcher.dict = dict
+	log.Printf("словарь запрещённых слов загружен, версия %d", dict.Version())
+
+	// Теневой словарь создаётся пустым, если файла нет.
+	if _, err := os.Stat(shadowDictionaryPath); errors.Is(err, os.ErrNotExist) {
+		err = os.WriteFile(shadowDictionaryPath, dictionary.Format(nil), 0644)
+		if err != nil {
+			log.Fatalf("ошибка создания теневого словаря:  %v", err)
+		}
+	}
+	shadowDict, err = dictionary.Load(shadowDictionaryPath)
+	if err != nil {
+		log.Fatalf("ошибка чтения теневого словаря:  %v", err)
+	}
+	shadowMatcher.dict = shadowDict
+
+	maxSize, err := strconv.ParseInt(auditMaxSize, 10, 64)
+	if err != nil || maxSize < 0 {
+		log.Fatalf("некорректный размер журнала проверок AUDIT_MAX_SIZE_MB %q", auditMaxSize)
+	}
+	maxFiles, err := strconv.Atoi(auditMaxFiles)
+	if err != nil || maxFiles < 0 {
+		log.Fatalf("некорректное число файлов журнала проверок AUDIT_MAX_FILES %q", auditMaxFiles)
+	}
+	auditLog, err = audit.Open(auditPath, auditSecret, audit.Options{MaxSize: maxSize << 20, MaxFiles: maxFiles})
+	if err != nil {
+		log.Fatalf("ошибка открытия журнала проверок:  %v", err)
+	}
+	if auditSecret == "" {
+		log.Println("не задан AUDIT_SECRET: адреса авторов в журнале проверок хэшируются без секрета")
+	}
+
+	// Словари перечитываются при изменении файла и по сигналу SIGHUP.
+	go dict.Watch(dictionaryWatchPeriod, chErrs)
+	go shadowDict.Watch(dictionaryWatchPeriod, chErrs)
+	go func() {
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		for range hup {
+			for _, d := range []*dictionary.Dictionary{dict, shadowDict} {
+				err := d.Reload()
+				if err != nil {
+					chErrs <- err
+				}
+			}
+			log.Printf("словари перечитаны, версия основного %d, теневого %d", dict.Version(), shadowDict.Version())
+		}
+	}()
+
 	r := mux.NewRouter()
-	r.HandleFunc("/verification", verification).Methods("POST") // проверка комментария на запрещённын слова.
+	r.HandleFunc("/verification", verification).Methods("POST")         // проверка комментария на запрещённын слова.
+	r.HandleFunc("/verification/confirm", confirmation).Methods("POST") // подтверждение сохранения принятого комментария
+
+	r.HandleFunc("/admin/dictionary", admin(dictionaryList)).Methods("GET")             // список слов словаря
+	r.HandleFunc("/admin/dictionary", admin(dictionaryAdd)).Methods("POST")             // добавление слов в словарь
+	r.HandleFunc("/admin/dictionary", admin(dictionaryRemove)).Methods("DELETE")        // удаление слов из словаря
+	r.HandleFunc("/admin/dictionary/promote", admin(dictionaryPromote)).Methods("POST") // замена основного словаря теневым
+	r.HandleFunc("/admin/audit", admin(auditList)).Methods("GET")                       // поиск в журнале проверок
 	http.Handle("/", r)
 	httpStart := fmt.Sprintf("HTTP server is started on localhost:%s", port)
 	fmt.Println(httpStart)
@@ -43,8 +211,20 @@ func main() {
 // проверка комментария на запрещённын слова.
 func verification(w http.ResponseWriter, r *http.Request) {
 
+	w.Header().Set("Content-Type", "application/json")
+
 	uniqueReqID := r.URL.Query().Get("request_id")
 
+	// Режим проверки: policy (по умолчанию) - действие по категории слова из конфигурации,
+	// mask - все найденные слова маскируются, комментарий не отклоняется.
+	mode := r.URL.Query().Get("mode")
+	if mode != "" && mode != modePolicy && mode != modeMask {
+		log.Printf("request_id %s: неизвестный режим проверки %q", uniqueReqID, mode)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var newComment Comment
 	decoder := json.NewDecoder(r.Body)
 	err := decoder.Decode(&newComment)
 	if err != nil {
@@ -53,20 +233,115 @@ func verification(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// приводим комментарий к нижнему регистру для сокращения числа образцов ругательств.
-	commentLower := strings.ToLower(newComment.Comment)
+	// Отклонённый комментарий - статус 400, в теле в обоих случаях результат проверки.
+	// IP-адрес автора передаётся APIGateway в заголовке X-Real-IP,
+	// источник новости (для выбора политики проверки) - в параметре source.
+	author, source := r.Header.Get("X-Real-IP"), r.URL.Query().Get("source")
+	verdict, shadowVerdict := verify(newComment, author, source, mode == modeMask)
 
-	for _, bad := range badWord {
-		match, err := regexp.MatchString(bad, commentLower)
-		if err != nil {
-			log.Printf("request_id %s: ошибка в поиске совпадений: %v", uniqueReqID, err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-		if match {
-			w.WriteHeader(http.StatusBadRequest)
-			return
+	// Решение записывается в журнал вместе с результатом по теневому словарю.
+	entry := auditEntry(verdict, shadowVerdict)
+	entry.RequestID = uniqueReqID
+	entry.NewsID = newComment.NewsID
+	entry.Source = source
+	entry.Author = auditLog.HashAuthor(author)
+	entry.Comment = newComment.Comment
+	err = auditLog.Append(entry)
+	if err != nil {
+		log.Printf("request_id %s: ошибка записи в журнал проверок: %v", uniqueReqID, err)
+	}
+	if entry.Shadow != nil && entry.Shadow.Decision != entry.Decision {
+		log.Printf("request_id %s: решение по теневому словарю (версия %d) отличается: %s вместо %s", uniqueReqID, entry.Shadow.DictionaryVersion, entry.Shadow.Decision, entry.Decision)
+	}
+
+	if !verdict.Allowed {
+		log.Printf("request_id %s: комментарий отклонён (%s), политика %s, оценка %d", uniqueReqID, verdict.Reason, verdict.Policy, verdict.Score)
+		w.WriteHeader(http.StatusBadRequest)
+	} else if verdict.Masked != "" {
+		log.Printf("request_id %s: в комментарии замаскированы запрещённые слова (%d)", uniqueReqID, len(verdict.Matches))
+	}
+	json.NewEncoder(w).Encode(verdict)
+}
+
+// подтверждение сохранения принятого комментария. APIGateway сообщает по идентификатору
+// проверки check_id, сохранён ли комментарий (stored=true|false): повтором считается только
+// сохранённый комментарий, несохранённый можно отправить ещё раз.
+func confirmation(w http.ResponseWriter, r *http.Request) {
+	uniqueReqID := r.URL.Query().Get("request_id")
+
+	checkID := r.URL.Query().Get("check_id")
+	stored, err := strconv.ParseBool(r.URL.Query().Get("stored"))
+	if checkID == "" || err != nil {
+		log.Printf("request_id %s: некорректное подтверждение проверки %q (stored %q)", uniqueReqID, checkID, r.URL.Query().Get("stored"))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if duplicates != nil {
+		duplicates.Confirm(checkID, stored)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// get возвращает правила для текущей версии словаря,
+// перестраивая их только при изменении словаря.
+func (c *matcherCache) get() *termMatcher {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.current != nil && c.current.version == c.dict.Version() {
+		return c.current
+	}
+	version, terms := c.dict.Snapshot()
+	c.current = newTermMatcher(version, terms)
+	log.Printf("правила поиска запрещённых слов построены для версии словаря %s %d (слов: %d, основ: %d, выражений: %d, исключений: %d)",
+		c.dict.Path(), version, len(c.current.wordRules), len(c.current.prefixRules), len(c.current.regexps), len(c.current.allow))
+	return c.current
+}
+
+// newTermMatcher подготавливает правила словаря версии version для поиска.
+func newTermMatcher(version int64, terms []dictionary.Term) *termMatcher {
+	tm := termMatcher{version: version, allow: make(map[string]bool)}
+	var words, prefixes []string
+	for _, t := range terms {
+		switch t.Type {
+		case dictionary.TypeWord:
+			tm.wordRules = append(tm.wordRules, t)
+			words = append(words, normalize.Term(t.Term))
+		case dictionary.TypePrefix:
+			tm.prefixRules = append(tm.prefixRules, t)
+			prefixes = append(prefixes, normalize.Term(stemLast(t.Term)))
+		case dictionary.TypeRegex:
+			re, err := dictionary.Compile(t.Term)
+			if err != nil {
+				// выражения проверяются при загрузке словаря
+				log.Printf("регулярное выражение %q пропущено: %v", t.Term, err)
+				continue
+			}
+			tm.regexps = append(tm.regexps, termRegexp{term: t, re: re})
+		case dictionary.TypeAllow:
+			tm.allow[normalize.Term(t.Term)] = true
 		}
 	}
-	w.WriteHeader(http.StatusOK)
+	tm.words = matcher.New(words)
+	tm.prefixes = matcher.New(prefixes)
+	return &tm
+}
+
+// empty сообщает, что в словаре нет запрещённых слов (есть только исключения или словарь пуст).
+func (tm *termMatcher) empty() bool {
+	return len(tm.wordRules) == 0 && len(tm.prefixRules) == 0 && len(tm.regexps) == 0
+}
+
+// stemLast заменяет основой последнее слово правила типа prefix ("грязный дурак" - "грязный дурак",
+// "дураки" - "дурак").
+func stemLast(term string) string {
+	i := strings.LastIndexAny(term, " ")
+	return term[:i+1] + stem.Stem(term[i+1:])
+}
+
+// envOr возвращает значение переменной окружения или значение по умолчанию.
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
 }
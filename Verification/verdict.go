@@ -0,0 +1,213 @@
+package main
+
+import (
+	"APIGateway/Verification/audit"
+	"APIGateway/Verification/config"
+	"APIGateway/Verification/normalize"
+	"APIGateway/Verification/spam"
+	"sort"
+	"time"
+	"unicode"
+
+	"github.com/rs/xid"
+)
+
+// Коды причин отклонения комментария.
+const (
+	ReasonBannedWords = "banned_words" // комментарий содержит запрещённые слова
+	ReasonSpam        = "spam"         // оценка комментария не меньше порога, есть признаки спама
+	ReasonDuplicate   = "duplicate"    // повтор недавнего комментария к той же новости или того же автора
+	ReasonFlood       = "flood"        // слишком много комментариев с одного IP-адреса
+	ReasonTooLong     = "too_long"     // оценка комментария не меньше порога из-за длины
+)
+
+// Режимы проверки (параметр mode запроса).
+const (
+	modePolicy = "policy" // действие определяется категорией слова по конфигурации
+	modeMask   = "mask"   // все найденные слова маскируются
+)
+
+// Результат проверки комментария.
+type Verdict struct {
+	Allowed           bool           `json:"Allowed"`          // комментарий можно публиковать
+	Reason            string         `json:"Reason,omitempty"` // код причины отклонения
+	Score             int            `json:"Score"`            // сумма серьёзности запрещённых слов и баллов признаков спама
+	Policy            string         `json:"Policy"`           // политика проверки
+	Matches           []VerdictMatch `json:"Matches,omitempty"`
+	Spam              []spam.Hit     `json:"Spam,omitempty"`    // признаки спама
+	DictionaryVersion int64          `json:"DictionaryVersion"` // версия словаря, по которой выполнена проверка
+	Masked            string         `json:"Masked,omitempty"`  // текст комментария с замаскированными словами (если есть слова с действием mask)
+	CheckID           string         `json:"CheckID,omitempty"` // идентификатор проверки для подтверждения сохранения принятого комментария
+}
+
+// Найденное в комментарии запрещённое слово. Позиции - в символах (Unicode code points)
+// исходного текста комментария, End - позиция за последним символом.
+type VerdictMatch struct {
+	Term     string `json:"Term"`     // слово словаря
+	Category string `json:"Category"` // категория слова
+	Severity int    `json:"Severity"` // серьёзность
+	Start    int    `json:"Start"`
+	End      int    `json:"End"`
+	Text     string `json:"Text"`   // найденный фрагмент комментария
+	Action   string `json:"Action"` // действие для категории слова: reject или mask
+}
+
+// verify проверяет комментарий правилами политики новости по основному словарю и,
+// если в теневом словаре есть слова, по теневому. Результат по теневому словарю не влияет
+// на решение. Политика выбирается по идентификатору новости и источнику новости source.
+// Действие для найденного слова определяется категорией слова; при forceMask все слова маскируются.
+// author - IP-адрес автора для проверки повторов и флуда (пустой - неизвестен).
+func verify(c Comment, author, source string, forceMask bool) (Verdict, *Verdict) {
+	policy := cfg.Policy(c.NewsID, source)
+	in := input{
+		comment:   c,
+		author:    author,
+		policy:    policy,
+		forceMask: forceMask,
+		tm:        activeMatcher.get(),
+		// нормализуем комментарий: регистр, похожие буквы, замены цифрами, разделители, повторы.
+		text:     normalize.Normalize(c.Comment),
+		original: []rune(c.Comment),
+		checkID:  xid.New().String(),
+		now:      time.Now(),
+	}
+	v, results := evaluate(&in, nil)
+
+	// По теневому словарю выполняются только правила словаря, результаты остальных
+	// правил берутся из проверки по основному словарю.
+	var shadow *Verdict
+	if tm := shadowMatcher.get(); !tm.empty() {
+		shadowIn := in
+		shadowIn.tm = tm
+		sv, _ := evaluate(&shadowIn, results)
+		shadow = &sv
+	}
+
+	// Правила запоминают только итог проверки по основному словарю. Сохранение
+	// принятого комментария подтверждается APIGateway по идентификатору проверки.
+	for _, name := range policy.Pipeline.Rules {
+		if r, ok := rules[name].(recorder); ok {
+			r.Record(&in, v.Allowed)
+			if v.Allowed {
+				v.CheckID = in.checkID
+			}
+		}
+	}
+	return v, shadow
+}
+
+// Правила, результат которых зависит от словаря.
+var dictionaryRules = map[string]bool{
+	config.RuleBannedWords: true,
+	config.RuleRegex:       true,
+}
+
+// evaluate выполняет правила политики и возвращает решение и результаты выполненных правил.
+// Если заданы результаты reuse прежней проверки того же комментария, заново выполняются
+// только правила словаря. Правило с состоянием (повторы и флуд), не выполнявшееся
+// в прежней проверке (режим short_circuit), пропускается.
+func evaluate(in *input, reuse map[string]ruleResult) (Verdict, map[string]ruleResult) {
+	policy := in.policy
+	v := Verdict{Allowed: true, DictionaryVersion: in.tm.version, Policy: policy.Name}
+	results := make(map[string]ruleResult, len(policy.Pipeline.Rules))
+
+	// Комментарий отклоняется первым правилом, отклонившим его безусловно или
+	// доведшим суммарную оценку до порога. В режиме short_circuit остальные правила
+	// после этого не выполняются, в режиме score - выполняются для полноты результата.
+	for _, name := range policy.Pipeline.Rules {
+		res, ok := reuse[name]
+		switch {
+		case reuse == nil || dictionaryRules[name]:
+			res = rules[name].Check(in)
+		case !ok:
+			if _, stateful := rules[name].(recorder); stateful {
+				continue
+			}
+			res = rules[name].Check(in)
+		}
+		results[name] = res
+		v.Matches = append(v.Matches, res.matches...)
+		v.Spam = append(v.Spam, res.spam...)
+		v.Score += res.score
+		if v.Allowed && res.reject != "" {
+			v.Allowed, v.Reason = false, res.reject
+		}
+		if v.Allowed && policy.Pipeline.Threshold > 0 && v.Score >= policy.Pipeline.Threshold {
+			v.Allowed, v.Reason = false, res.reason
+		}
+		if !v.Allowed && policy.Pipeline.Mode == config.ModeShortCircuit {
+			break
+		}
+	}
+	sort.SliceStable(v.Matches, func(i, j int) bool { return v.Matches[i].Start < v.Matches[j].Start })
+
+	// Найденные слова принятого комментария маскируются.
+	if v.Allowed && len(v.Matches) > 0 {
+		v.Masked = mask(in.original, v.Matches, cfg.Mask())
+	}
+	return v, results
+}
+
+// auditEntry заполняет запись журнала по результатам проверки.
+func auditEntry(v Verdict, shadow *Verdict) audit.Entry {
+	e := audit.Entry{
+		Time:              time.Now().Unix(),
+		Policy:            v.Policy,
+		Decision:          decision(v),
+		Reason:            v.Reason,
+		Score:             v.Score,
+		Rules:             rulesHit(v),
+		DictionaryVersion: v.DictionaryVersion,
+	}
+	if shadow != nil {
+		e.Shadow = &audit.Result{
+			DictionaryVersion: shadow.DictionaryVersion,
+			Decision:          decision(*shadow),
+			Reason:            shadow.Reason,
+			Score:             shadow.Score,
+			Rules:             rulesHit(*shadow),
+		}
+	}
+	return e
+}
+
+// decision возвращает решение проверки для журнала.
+func decision(v Verdict) string {
+	switch {
+	case !v.Allowed:
+		return audit.DecisionReject
+	case v.Masked != "":
+		return audit.DecisionMask
+	}
+	return audit.DecisionAllow
+}
+
+// rulesHit возвращает сработавшие правила: "категория:слово" для слов словаря,
+// "spam:проверка" для признаков спама, код причины для повторов и флуда.
+func rulesHit(v Verdict) []string {
+	var hit []string
+	for _, m := range v.Matches {
+		hit = append(hit, m.Category+":"+m.Term)
+	}
+	for _, h := range v.Spam {
+		hit = append(hit, "spam:"+h.Check)
+	}
+	if v.Reason == ReasonDuplicate || v.Reason == ReasonFlood {
+		hit = append(hit, v.Reason)
+	}
+	return hit
+}
+
+// mask заменяет символом маски все непробельные символы найденных фрагментов.
+// Перекрывающиеся фрагменты маскируются один раз.
+func mask(original []rune, matches []VerdictMatch, maskChar rune) string {
+	out := append([]rune(nil), original...)
+	for _, m := range matches {
+		for i := m.Start; i < m.End; i++ {
+			if !unicode.IsSpace(out[i]) {
+				out[i] = maskChar
+			}
+		}
+	}
+	return string(out)
+}
@@ -5,13 +5,15 @@ go 1.22.0
 require (
 	github.com/go-chi/chi v1.5.5
 	github.com/gorilla/mux v1.8.1
-	github.com/jackc/pgconn v1.14.1
 	github.com/jackc/pgx/v4 v4.18.1
 	github.com/rs/xid v1.5.0
+	golang.org/x/net v0.21.0
+	golang.org/x/text v0.14.0
 )
 
 require (
 	github.com/jackc/chunkreader/v2 v2.0.1 // indirect
+	github.com/jackc/pgconn v1.14.1 // indirect
 	github.com/jackc/pgio v1.0.0 // indirect
 	github.com/jackc/pgpassfile v1.0.0 // indirect
 	github.com/jackc/pgproto3/v2 v2.3.2 // indirect
@@ -21,5 +23,4 @@ require (
 	github.com/lib/pq v1.10.9 // indirect
 	github.com/stretchr/testify v1.9.0 // indirect
 	golang.org/x/crypto v0.19.0 // indirect
-	golang.org/x/text v0.14.0 // indirect
 )
@@ -0,0 +1,92 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// ErrUsage возвращается Command при неверных аргументах подкоманды migrate.
+// Сервис в этом случае выводит свою справку по подкомандам.
+var ErrUsage = errors.New("неверные аргументы подкоманды migrate")
+
+// Хранилище сервиса, выполняющее миграции своей схемы БД.
+type Runner interface {
+	MigrateUp() ([]Migration, error)
+	MigrateDown(steps int) ([]Migration, error)
+	MigrationStatus() ([]Status, error)
+}
+
+// Command выполняет подкоманду командной строки migrate сервиса:
+//
+//	migrate up          применить миграции схемы БД
+//	migrate down [N]    отменить N последних миграций (по умолчанию - одну)
+//	migrate status      состояние миграций
+//
+// args - аргументы после migrate. К БД подключается connect после проверки аргументов.
+// Результат выводится в out.
+func Command(args []string, connect func() (Runner, error), out io.Writer) error {
+	steps := 1
+	switch {
+	case len(args) == 1 && (args[0] == "up" || args[0] == "status"):
+	case len(args) >= 1 && args[0] == "down" && len(args) <= 2:
+		if len(args) == 2 {
+			var err error
+			steps, err = strconv.Atoi(args[1])
+			if err != nil {
+				return fmt.Errorf("число отменяемых миграций %s: %v", args[1], err)
+			}
+			if steps <= 0 {
+				return fmt.Errorf("число отменяемых миграций %s: должно быть больше нуля", args[1])
+			}
+		}
+	default:
+		return ErrUsage
+	}
+
+	db, err := connect()
+	if err != nil {
+		return fmt.Errorf("ошибка подключения к БД:  %v", err)
+	}
+
+	switch args[0] {
+	case "up":
+		done, err := db.MigrateUp()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "применено миграций: %d\n", len(done))
+	case "down":
+		done, err := db.MigrateDown(steps)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "отменено миграций: %d\n", len(done))
+	case "status":
+		status, err := db.MigrationStatus()
+		if err != nil {
+			return err
+		}
+		printStatus(out, status)
+	}
+	return nil
+}
+
+// printStatus выводит состояние миграций: по строке на миграцию.
+func printStatus(out io.Writer, status []Status) {
+	for _, st := range status {
+		state := "не применена"
+		if st.Applied {
+			state = "применена " + time.Unix(st.AppliedAt, 0).UTC().Format(time.RFC3339)
+		}
+		if st.Modified {
+			state += ", файл изменён после применения"
+		}
+		if st.Missing {
+			state += ", отсутствует в сервисе"
+		}
+		fmt.Fprintf(out, "%04d_%s\t%s\n", st.Version, st.Name, state)
+	}
+}
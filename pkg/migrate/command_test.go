@@ -0,0 +1,99 @@
+package migrate
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Хранилище для проверки Command без БД.
+type testRunner struct {
+	steps  int // число отменяемых миграций, переданное MigrateDown
+	status []Status
+}
+
+func (r *testRunner) MigrateUp() ([]Migration, error) {
+	return []Migration{{Version: 1}, {Version: 2}}, nil
+}
+
+func (r *testRunner) MigrateDown(steps int) ([]Migration, error) {
+	r.steps = steps
+	return make([]Migration, steps), nil
+}
+
+func (r *testRunner) MigrationStatus() ([]Status, error) {
+	return r.status, nil
+}
+
+func TestCommand(t *testing.T) {
+	applied := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC).Unix()
+	tests := []struct {
+		name      string
+		args      []string
+		wantErr   string // начало текста ошибки; пустое - без ошибки
+		wantOut   string
+		wantSteps int
+	}{
+		{name: "up", args: []string{"up"}, wantOut: "применено миграций: 2\n"},
+		{name: "down по умолчанию", args: []string{"down"}, wantOut: "отменено миграций: 1\n", wantSteps: 1},
+		{name: "down N", args: []string{"down", "3"}, wantOut: "отменено миграций: 3\n", wantSteps: 3},
+		{name: "down 0", args: []string{"down", "0"}, wantErr: "число отменяемых миграций 0: должно быть больше нуля"},
+		{name: "down -1", args: []string{"down", "-1"}, wantErr: "число отменяемых миграций -1: должно быть больше нуля"},
+		{name: "down не число", args: []string{"down", "x"}, wantErr: "число отменяемых миграций x: strconv.Atoi"},
+		{
+			name: "status",
+			args: []string{"status"},
+			wantOut: "0001_baseline\tприменена 2024-03-01T12:00:00Z, файл изменён после применения\n" +
+				"0002_legacy\tне применена\n" +
+				"0003_old\tприменена 2024-03-01T12:00:00Z, отсутствует в сервисе\n",
+		},
+		{name: "без подкоманды", args: nil, wantErr: ErrUsage.Error()},
+		{name: "неизвестная подкоманда", args: []string{"redo"}, wantErr: ErrUsage.Error()},
+		{name: "лишний аргумент up", args: []string{"up", "1"}, wantErr: ErrUsage.Error()},
+		{name: "лишний аргумент down", args: []string{"down", "1", "2"}, wantErr: ErrUsage.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &testRunner{status: []Status{
+				{Version: 1, Name: "baseline", Applied: true, AppliedAt: applied, Modified: true},
+				{Version: 2, Name: "legacy"},
+				{Version: 3, Name: "old", Applied: true, AppliedAt: applied, Missing: true},
+			}}
+			connected := false
+			var out bytes.Buffer
+			err := Command(tt.args, func() (Runner, error) {
+				connected = true
+				return r, nil
+			}, &out)
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Fatalf("Command(%q) err = %v, want %q", tt.args, err, tt.wantErr)
+				}
+				if connected {
+					t.Errorf("Command(%q) подключился к БД при неверных аргументах", tt.args)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Command(%q) err = %v", tt.args, err)
+			}
+			if got := out.String(); got != tt.wantOut {
+				t.Errorf("Command(%q) вывод = %q, want %q", tt.args, got, tt.wantOut)
+			}
+			if r.steps != tt.wantSteps {
+				t.Errorf("MigrateDown(%d), want %d", r.steps, tt.wantSteps)
+			}
+		})
+	}
+}
+
+func TestCommand_connectError(t *testing.T) {
+	err := Command([]string{"up"}, func() (Runner, error) {
+		return nil, errors.New("connection refused")
+	}, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("Command() err = %v, want ошибку подключения", err)
+	}
+}
@@ -0,0 +1,304 @@
+// Пакет migrate применяет и отменяет версионированные миграции схемы БД PostgreSQL.
+// Миграции встраиваются в сервис (embed.FS) файлами migrations/<версия>_<название>.up.sql
+// и парными .down.sql; применённые миграции учитываются в таблице schema_migrations.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+var migrationName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Миграция схемы БД.
+type Migration struct {
+	Version  int    // номер версии схемы
+	Name     string // название миграции
+	Up       string // SQL применения миграции
+	Down     string // SQL отмены миграции
+	Checksum string // sha256 SQL применения
+}
+
+// Состояние миграции в БД.
+type Status struct {
+	Version   int    `json:"Version"`
+	Name      string `json:"Name"`
+	Applied   bool   `json:"Applied"`             // миграция применена
+	AppliedAt int64  `json:"AppliedAt,omitempty"` // время применения (Unix)
+	Modified  bool   `json:"Modified,omitempty"`  // файл миграции изменён после применения
+	Missing   bool   `json:"Missing,omitempty"`   // миграция применена, но отсутствует в сервисе
+}
+
+// Исполнитель миграций схемы БД сервиса.
+type Migrator struct {
+	db      *pgxpool.Pool
+	files   embed.FS // файлы миграций в каталоге migrations
+	lockKey int64    // ключ блокировки pg_advisory_lock
+}
+
+// New возвращает исполнитель миграций из files для БД db. Ключ lockKey блокировки
+// pg_advisory_lock исключает одновременное выполнение миграций несколькими
+// экземплярами сервиса и должен быть своим у каждой БД.
+func New(db *pgxpool.Pool, files embed.FS, lockKey int64) *Migrator {
+	return &Migrator{db: db, files: files, lockKey: lockKey}
+}
+
+// Применённая миграция из таблицы schema_migrations.
+type appliedMigration struct {
+	Name      string
+	Checksum  string
+	AppliedAt int64
+}
+
+// loadMigrations читает встроенные файлы миграций, упорядоченные по версии.
+func (m *Migrator) load() ([]Migration, error) {
+	files, err := fs.Glob(m.files, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int]*Migration)
+	for _, file := range files {
+		base := file[len("migrations/"):]
+		parts := migrationName.FindStringSubmatch(base)
+		if parts == nil {
+			return nil, fmt.Errorf("имя файла миграции %s не соответствует формату <версия>_<название>.up|down.sql", base)
+		}
+		version, _ := strconv.Atoi(parts[1])
+		b, err := m.files.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		mg, ok := byVersion[version]
+		if !ok {
+			mg = &Migration{Version: version, Name: parts[2]}
+			byVersion[version] = mg
+		} else if mg.Name != parts[2] {
+			return nil, fmt.Errorf("у миграции %d разные названия: %s и %s", version, mg.Name, parts[2])
+		}
+		if parts[3] == "up" {
+			mg.Up = string(b)
+			sum := sha256.Sum256(b)
+			mg.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			mg.Down = string(b)
+		}
+	}
+
+	var migrations []Migration
+	for _, mg := range byVersion {
+		if mg.Up == "" || mg.Down == "" {
+			return nil, fmt.Errorf("у миграции %d_%s нет файла up или down", mg.Version, mg.Name)
+		}
+		migrations = append(migrations, *mg)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// withLock выполняет fn на отдельном соединении под блокировкой миграций,
+// предварительно создав таблицу schema_migrations.
+func (m *Migrator) withLock(fn func(conn *pgxpool.Conn) error) error {
+	ctx := context.Background()
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, `SELECT pg_advisory_lock($1);`, m.lockKey)
+	if err != nil {
+		return fmt.Errorf("блокировка миграций: %v", err)
+	}
+	defer conn.Exec(ctx, `SELECT pg_advisory_unlock($1);`, m.lockKey)
+
+	_, err = conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			checksum TEXT NOT NULL,
+			applied_at BIGINT NOT NULL
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("создание таблицы schema_migrations: %v", err)
+	}
+	return fn(conn)
+}
+
+// appliedMigrations возвращает применённые миграции по версиям.
+func appliedMigrations(conn *pgxpool.Conn) (map[int]appliedMigration, error) {
+	rows, err := conn.Query(context.Background(), `
+		SELECT
+			version,
+			name,
+			checksum,
+			applied_at
+		FROM schema_migrations;
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]appliedMigration)
+	for rows.Next() {
+		var version int
+		var a appliedMigration
+		err = rows.Scan(&version, &a.Name, &a.Checksum, &a.AppliedAt)
+		if err != nil {
+			return nil, err
+		}
+		applied[version] = a
+	}
+	return applied, rows.Err()
+}
+
+// verifyMigrations проверяет, что применённые миграции не изменены и известны сервису.
+func verifyMigrations(migrations []Migration, applied map[int]appliedMigration) error {
+	known := make(map[int]bool, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = true
+		if a, ok := applied[m.Version]; ok && a.Checksum != m.Checksum {
+			return fmt.Errorf("миграция %d_%s изменена после применения (контрольная сумма %s, в БД %s)", m.Version, m.Name, m.Checksum, a.Checksum)
+		}
+	}
+	for version, a := range applied {
+		if !known[version] {
+			return fmt.Errorf("в БД применена миграция %d_%s, отсутствующая в сервисе", version, a.Name)
+		}
+	}
+	return nil
+}
+
+// Up применяет все ещё не применённые миграции. Каждая миграция выполняется
+// в отдельной транзакции. Возвращает применённые миграции.
+func (m *Migrator) Up() ([]Migration, error) {
+	migrations, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	var done []Migration
+	err = m.withLock(func(conn *pgxpool.Conn) error {
+		applied, err := appliedMigrations(conn)
+		if err != nil {
+			return err
+		}
+		err = verifyMigrations(migrations, applied)
+		if err != nil {
+			return err
+		}
+		for _, mg := range migrations {
+			if _, ok := applied[mg.Version]; ok {
+				continue
+			}
+			err = runMigration(conn, mg, mg.Up, `
+				INSERT INTO schema_migrations (version, name, checksum, applied_at)
+				VALUES ($1, $2, $3, $4);
+			`, mg.Version, mg.Name, mg.Checksum, time.Now().Unix())
+			if err != nil {
+				return err
+			}
+			log.Printf("применена миграция %d_%s", mg.Version, mg.Name)
+			done = append(done, mg)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down отменяет steps последних применённых миграций. Возвращает отменённые миграции.
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	migrations, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	var done []Migration
+	err = m.withLock(func(conn *pgxpool.Conn) error {
+		applied, err := appliedMigrations(conn)
+		if err != nil {
+			return err
+		}
+		err = verifyMigrations(migrations, applied)
+		if err != nil {
+			return err
+		}
+		for i := len(migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			mg := migrations[i]
+			if _, ok := applied[mg.Version]; !ok {
+				continue
+			}
+			err = runMigration(conn, mg, mg.Down, `
+				DELETE FROM schema_migrations WHERE version = $1;
+			`, mg.Version)
+			if err != nil {
+				return err
+			}
+			log.Printf("отменена миграция %d_%s", mg.Version, mg.Name)
+			done = append(done, mg)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// runMigration выполняет SQL миграции и запрос к schema_migrations в одной транзакции.
+func runMigration(conn *pgxpool.Conn, m Migration, migrationSQL, recordSQL string, args ...interface{}) error {
+	ctx := context.Background()
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, migrationSQL)
+	if err != nil {
+		return fmt.Errorf("миграция %d_%s: %v", m.Version, m.Name, err)
+	}
+	_, err = tx.Exec(ctx, recordSQL, args...)
+	if err != nil {
+		return fmt.Errorf("миграция %d_%s: запись в schema_migrations: %v", m.Version, m.Name, err)
+	}
+	return tx.Commit(ctx)
+}
+
+// Status возвращает состояние всех миграций: известных сервису и применённых в БД.
+func (m *Migrator) Status() ([]Status, error) {
+	migrations, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	var status []Status
+	err = m.withLock(func(conn *pgxpool.Conn) error {
+		applied, err := appliedMigrations(conn)
+		if err != nil {
+			return err
+		}
+		for _, mg := range migrations {
+			st := Status{Version: mg.Version, Name: mg.Name}
+			if a, ok := applied[mg.Version]; ok {
+				st.Applied = true
+				st.AppliedAt = a.AppliedAt
+				st.Modified = a.Checksum != mg.Checksum
+				delete(applied, mg.Version)
+			}
+			status = append(status, st)
+		}
+		for version, a := range applied {
+			status = append(status, Status{Version: version, Name: a.Name, Applied: true, AppliedAt: a.AppliedAt, Missing: true})
+		}
+		return nil
+	})
+	sort.Slice(status, func(i, j int) bool { return status[i].Version < status[j].Version })
+	return status, err
+}